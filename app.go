@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
-	"os/exec"
-	"strings"
+	"net"
+	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/wailsapp/wails/v3/pkg/application"
@@ -15,42 +17,144 @@ type App struct {
 	window   *application.WebviewWindow
 	wailsApp *application.App
 
-	prevAppPID string // PID of the app that was frontmost before we showed
+	prevApp string // Platform handle (macOS PID, X11 window ID) of the app that was frontmost before we showed; guarded by mu
+
+	clip    ClipboardBackend            // System clipboard (or a fake in tests)
+	emit    func(name string, data any) // Sends events to the frontend; nil until Wails is attached
+	openURL func(url string) error      // Opens a URL with the system handler; nil until Wails is attached
 
 	// Clipboard history
-	mu              sync.Mutex
-	history         []ClipItem
-	lastChangeCount int
-	lastWritten     string    // Tracks text we just wrote to clipboard (to avoid re-capturing)
-	lastPasteTime   time.Time // Timestamp of last paste to prevent re-capturing our own paste
+	mu         sync.Mutex
+	history    []ClipItem
+	imageIndex map[string]string // Image SHA-256 → ID of the item holding it, for O(1) dedup
+	revision   uint64            // Bumped on every history mutation, see HistoryChange
+	pauseState PauseStatus       // Clipboard changes are ignored while paused, see setPause
+	pauseTimer *time.Timer       // Resumes a timed pause
+
+	// Clipboard watcher, see watchClipboard
+	clock       clock      // Time source of the watcher and pauses; nil means the system clock
+	clipWriteMu sync.Mutex // Held from a clipboard write until ownCount records it
+	ownCount    int        // Change count our last clipboard write produced, guarded by clipWriteMu
+
+	settings Settings         // Guarded by mu
+	excludes []*regexp.Regexp // Settings.ExcludePatterns compiled by applySettings, guarded by mu
+	dataDir  string           // Where history is persisted; empty disables persistence
+
+	// Settings file, see watchSettings; empty settingsPath keeps settings in memory only.
+	// settingsMu makes writing or reading the file, noting its mod time and
+	// applying the settings one step, so UpdateSettings and
+	// reloadSettingsIfChanged never interleave.
+	settingsPath    string
+	settingsMu      sync.Mutex
+	settingsModTime time.Time // Guarded by settingsMu
+
+	// Global hotkeys, see startHotkeys
+	hotkeyMu      sync.Mutex
+	hotkeysActive bool
+	hotkeys       map[string]*hotkeyBinding // Registered bindings by action
+	hotkeyStatus  []HotkeyStatus
+	showMenuItem  *application.MenuItem // Tray item labelled with the show hotkey
+
+	// Tray icon and the pause item reflecting the pause state, see showPauseState
+	tray          *application.SystemTray
+	pauseMenuItem *application.MenuItem
+
+	// Background workers and shutdown, see runBackground
+	stopBackground context.CancelFunc
+	background     sync.WaitGroup
+	shutdownOnce   sync.Once
+	stopping       atomic.Bool  // Set once shutdown starts; forwarded commands are refused
+	instance       net.Listener // Single-instance socket, see serveInstance; nil if not held
+
+	// Debounced persistence, see scheduleSave
+	saveMu      sync.Mutex
+	saveDirty   bool
+	saveTimer   *time.Timer
+	saveWriteMu sync.Mutex
+	blobs       *blobStore                   // Image files; created on first use, guarded by saveWriteMu
+	savedBlobs  [historyBackups + 1][]string // Image hashes referenced by history.json ([0]) and backup n ([n])
+	lastBackup  time.Time                    // When the backups last rotated, see historyBackupInterval; guarded by saveWriteMu
+
+	// Encryption at rest, see cryptor
+	keyring    keyring       // Where the history key lives; nil if the platform has none
+	crypt      *cryptor      // Created on first use, guarded by saveWriteMu
+	storage    StorageStatus // Guarded by mu
+	loadFailed bool          // History on disk could not be read; saving would destroy it. Guarded by mu
+
+	// Backups and the set-aside file are kept encrypted like this, see
+	// setBackupsEncrypted; guarded by saveWriteMu
+	backupsEncrypted bool
+	backupsModeSet   bool
+}
+
+// Height bounds of the island window; Settings.WindowHeight must lie within them.
+const (
+	minWindowHeight = 120
+	maxWindowHeight = 800
+)
+
+// NewApp creates the App service on top of the given clipboard backend.
+func NewApp(clip ClipboardBackend) *App {
+	return &App{clip: clip, imageIndex: make(map[string]string)}
 }
 
 // capturePreviousApp records which app currently has focus so we can restore it later.
 func (a *App) capturePreviousApp() {
-	out, err := exec.Command("osascript", "-e",
-		`tell application "System Events" to get unix id of first process whose frontmost is true`,
-	).Output()
+	id, err := frontmostApp()
 	if err != nil {
 		log.Printf("[clipboard] capturePreviousApp failed: %v", err)
-		a.prevAppPID = ""
-		return
 	}
-	a.prevAppPID = strings.TrimSpace(string(out))
+	a.mu.Lock()
+	a.prevApp = id
+	a.mu.Unlock()
 }
 
 // restorePreviousApp re-activates the app that was focused before the island appeared.
 func (a *App) restorePreviousApp() {
-	if a.prevAppPID == "" {
+	a.mu.Lock()
+	id := a.prevApp
+	a.prevApp = ""
+	a.mu.Unlock()
+	if id == "" {
 		return
 	}
-	pid := a.prevAppPID
-	a.prevAppPID = ""
-	script := `tell application "System Events" to set frontmost of (first process whose unix id is ` + pid + `) to true`
-	if err := exec.Command("osascript", "-e", script).Run(); err != nil {
+	if err := activateApp(id); err != nil {
 		log.Printf("[clipboard] restorePreviousApp failed: %v", err)
 	}
 }
 
+// showIsland positions the island at the cursor, shows it and tells the
+// frontend to open. Used by the hotkey and the tray menu.
+func (a *App) showIsland() {
+	if a.window == nil {
+		return
+	}
+	a.capturePreviousApp()
+	settings := a.GetSettings()
+	cx, cy, sw, sh, scale := cursorAndScreen()
+	ww := settings.WindowWidth * scale
+	wh := settings.WindowHeight * scale
+	wx, wy := calcWindowPosition(cx, cy, ww, wh, sw, sh)
+	a.window.SetSize(settings.WindowWidth, settings.WindowHeight)
+	a.window.SetPosition(wx, wy)
+	a.window.Show()
+	a.window.Focus()
+	if a.emit != nil {
+		a.emit("hotkey", nil)
+	}
+}
+
+// resizeWindow applies a new island size; the width is fixed, so the
+// window's size bounds move with it.
+func (a *App) resizeWindow(settings Settings) {
+	if a.window == nil {
+		return
+	}
+	a.window.SetMinSize(settings.WindowWidth, minWindowHeight)
+	a.window.SetMaxSize(settings.WindowWidth, maxWindowHeight)
+	a.window.SetSize(settings.WindowWidth, settings.WindowHeight)
+}
+
 // HideWindow is called by the frontend on Escape — dismiss without pasting.
 func (a *App) HideWindow() {
 	a.window.Hide()
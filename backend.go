@@ -0,0 +1,56 @@
+package main
+
+// ClipFormat identifies a clipboard representation by MIME type.
+type ClipFormat string
+
+const (
+	FormatText  ClipFormat = "text/plain"
+	FormatImage ClipFormat = "image/png"
+	FormatHTML  ClipFormat = "text/html"
+	FormatRTF   ClipFormat = "text/rtf"
+	FormatFiles ClipFormat = "text/uri-list" // file:// URLs, one per line
+
+	// Markers that password managers and other apps add to say a clip must
+	// not be recorded (concealed) or is short-lived (transient). Only their
+	// presence matters, see http://nspasteboard.org.
+	FormatConcealed ClipFormat = "org.nspasteboard.ConcealedType"
+	FormatTransient ClipFormat = "org.nspasteboard.TransientType"
+)
+
+// richFormats are captured alongside a text clip and restored with it.
+var richFormats = []ClipFormat{FormatHTML, FormatRTF, FormatFiles}
+
+// ClipboardBackend is the system clipboard that App captures from and pastes into.
+// Platform implementations live in the *_<GOOS>.go files; tests use a fake.
+// Implementations must be safe for concurrent use.
+type ClipboardBackend interface {
+	// ChangeCount returns a counter that increases every time the clipboard changes.
+	ChangeCount() int
+
+	// Read returns the clipboard content in the given format, or nil if
+	// absent. Present but empty content is a non-nil empty slice.
+	Read(format ClipFormat) []byte
+
+	// Write replaces the clipboard content with all the given representations
+	// at once. Backends that cannot offer several formats keep the text or image.
+	// It returns the change count the write produced, so the watcher can tell
+	// its own writes from the user's copies, or an error if the clipboard
+	// was left as it was.
+	Write(data map[ClipFormat][]byte) (int, error)
+
+	// SourceApp identifies the application that is frontmost, and so most
+	// likely copied the current content: a bundle ID on macOS, a WM_CLASS
+	// on X11. It returns "" where the platform cannot tell.
+	SourceApp() string
+
+	// Paste sends the platform paste keystroke to the focused application.
+	Paste() error
+}
+
+// ChangeNotifier is implemented by backends that hear about clipboard changes
+// from the system. The watcher waits on Changes instead of polling quickly.
+type ChangeNotifier interface {
+	// Changes returns a channel that receives a value after the change
+	// count increases. Several changes may be coalesced into one value.
+	Changes() <-chan struct{}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBackend is an in-memory ClipboardBackend and ChangeNotifier for tests.
+// Wrap it in pollingBackend to hide the notifications.
+type fakeBackend struct {
+	mu      sync.Mutex
+	count   int
+	data    map[ClipFormat][]byte
+	source  string        // Returned by SourceApp
+	failing error         // Returned by Write, which then changes nothing
+	pasted  chan []byte   // Receives the text content on every Paste
+	changes chan struct{} // Signalled on every Write
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		data:    make(map[ClipFormat][]byte),
+		pasted:  make(chan []byte, 16),
+		changes: make(chan struct{}, 1),
+	}
+}
+
+// pollingBackend hides the ChangeNotifier of the backend it embeds.
+type pollingBackend struct {
+	ClipboardBackend
+}
+
+func (f *fakeBackend) ChangeCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.count
+}
+
+func (f *fakeBackend) Read(format ClipFormat) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.data[format]
+}
+
+func (f *fakeBackend) Write(data map[ClipFormat][]byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failing != nil {
+		return f.count, f.failing
+	}
+	f.data = make(map[ClipFormat][]byte, len(data))
+	for format, b := range data {
+		f.data[format] = bytes.Clone(b)
+	}
+	f.count++
+	select {
+	case f.changes <- struct{}{}:
+	default:
+	}
+	return f.count, nil
+}
+
+func (f *fakeBackend) Changes() <-chan struct{} {
+	return f.changes
+}
+
+func (f *fakeBackend) SourceApp() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.source
+}
+
+func (f *fakeBackend) Paste() error {
+	f.pasted <- f.Read(FormatText)
+	return nil
+}
+
+// copyText simulates the user copying text in another application.
+func (f *fakeBackend) copyText(text string) {
+	f.Write(map[ClipFormat][]byte{FormatText: []byte(text)})
+}
+
+// TestPollClipboard_CapturesText verifies the watcher captures copied text into history.
+func TestPollClipboard_CapturesText(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	state := &watchState{lastCount: fake.ChangeCount()}
+
+	if app.pollClipboard(state) {
+		t.Error("expected no change before anything was copied")
+	}
+
+	fake.copyText("hello")
+	if !app.pollClipboard(state) {
+		t.Error("expected change after copy")
+	}
+	fake.copyText("world")
+	app.pollClipboard(state)
+
+	if len(app.history) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(app.history))
+	}
+	if app.history[0].Text != "world" || app.history[1].Text != "hello" {
+		t.Errorf("unexpected history order: %q, %q", app.history[0].Text, app.history[1].Text)
+	}
+}
+
+// TestPollClipboard_CapturesImage verifies images are preferred over text.
+func TestPollClipboard_CapturesImage(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	state := &watchState{lastCount: fake.ChangeCount()}
+
+	fakeImage := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
+	fake.Write(map[ClipFormat][]byte{FormatImage: append(fakeImage, make([]byte, 100)...)})
+	app.pollClipboard(state)
+
+	if len(app.history) != 1 || app.history[0].Type != TypeImage {
+		t.Fatalf("expected 1 image item, got %+v", app.history)
+	}
+}
+
+// TestSelectItem_PastesAndSkipsOwnWrite drives the full watcher → history → paste loop.
+func TestSelectItem_PastesAndSkipsOwnWrite(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	state := &watchState{lastCount: fake.ChangeCount()}
+
+	fake.copyText("first")
+	app.pollClipboard(state)
+	fake.copyText("second")
+	app.pollClipboard(state)
+
+	app.SelectItem(app.history[1].ID) // "first"
+
+	select {
+	case got := <-fake.pasted:
+		if string(got) != "first" {
+			t.Errorf("expected paste of 'first', got %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("paste was never simulated")
+	}
+
+	// The watcher sees our own write and must not re-capture it.
+	app.pollClipboard(state)
+	if len(app.history) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(app.history))
+	}
+	if app.history[0].Text != "second" {
+		t.Errorf("own write was re-captured: first item is %q", app.history[0].Text)
+	}
+}
+
+// TestSelectItem_WriteFailureSkipsPaste verifies a failed clipboard write is
+// reported and no paste keystroke is sent.
+func TestSelectItem_WriteFailureSkipsPaste(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	app.addItem("first")
+	app.addItem("second")
+
+	fake.mu.Lock()
+	fake.failing = errors.New("no owner")
+	fake.mu.Unlock()
+	if err := app.SelectItem(app.history[1].ID); err == nil {
+		t.Fatal("expected the write failure reported")
+	}
+
+	select {
+	case got := <-fake.pasted:
+		t.Errorf("expected no paste, got %q", got)
+	case <-time.After(300 * time.Millisecond):
+	}
+	if app.history[1].UseCount != 0 {
+		t.Errorf("expected no use recorded, got %d", app.history[1].UseCount)
+	}
+}
+
+// TestPollClipboard_Paused verifies nothing is captured while paused, and
+// content copied during the pause is not captured on resume.
+func TestPollClipboard_Paused(t *testing.T) {
+	clip := newFakeBackend()
+	app := NewApp(clip)
+	state := &watchState{lastCount: clip.ChangeCount()}
+
+	app.togglePause()
+	clip.copyText("secret")
+	app.pollClipboard(state)
+	app.togglePause()
+	app.pollClipboard(state)
+	if len(app.GetHistory()) != 0 {
+		t.Fatalf("expected nothing captured while paused, got %+v", app.GetHistory())
+	}
+
+	clip.copyText("after")
+	app.pollClipboard(state)
+	if h := app.GetHistory(); len(h) != 1 || h[0].Text != "after" {
+		t.Errorf("expected capture after resume, got %+v", h)
+	}
+}
+
+// TestPastePrevious verifies the second most recent item is pasted.
+func TestPastePrevious(t *testing.T) {
+	clip := newFakeBackend()
+	app := NewApp(clip)
+	app.addItem("older")
+	app.addItem("newer")
+
+	app.pastePrevious()
+	select {
+	case data := <-clip.pasted:
+		if string(data) != "older" {
+			t.Errorf("expected 'older' pasted, got %q", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for paste")
+	}
+}
+
+// TestPastePrevious_Concurrent verifies hotkey presses racing each other
+// and the pastes they start share the previous app safely; run with -race.
+func TestPastePrevious_Concurrent(t *testing.T) {
+	clip := newFakeBackend()
+	app := NewApp(clip)
+	app.addItem("older")
+	app.addItem("newer")
+
+	const presses = 4
+	for range presses {
+		go app.pastePrevious()
+	}
+	for range presses {
+		select {
+		case <-clip.pasted:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for paste")
+		}
+	}
+}
+
+// TestPollClipboard_CapturesRichFormats verifies HTML and RTF are stored
+// with the text and all representations are restored on SelectItem.
+func TestPollClipboard_CapturesRichFormats(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	state := &watchState{lastCount: fake.ChangeCount()}
+
+	fake.Write(map[ClipFormat][]byte{
+		FormatText: []byte("bold"),
+		FormatHTML: []byte("<b>bold</b>"),
+		FormatRTF:  []byte(`{\rtf1 \b bold}`),
+	})
+	app.pollClipboard(state)
+
+	if len(app.history) != 1 || app.history[0].Text != "bold" {
+		t.Fatalf("expected one 'bold' item, got %+v", app.history)
+	}
+	if got := string(app.history[0].Formats[FormatHTML]); got != "<b>bold</b>" {
+		t.Errorf("expected HTML to be captured, got %q", got)
+	}
+
+	fake.copyText("other")
+	app.pollClipboard(state)
+	app.SelectItem(app.history[1].ID)
+	<-fake.pasted
+
+	for format, want := range map[ClipFormat]string{
+		FormatText: "bold",
+		FormatHTML: "<b>bold</b>",
+		FormatRTF:  `{\rtf1 \b bold}`,
+	} {
+		if got := string(fake.Read(format)); got != want {
+			t.Errorf("%s: expected %q restored, got %q", format, want, got)
+		}
+	}
+}
+
+// TestPollClipboard_FileListOnly verifies a file list without text becomes
+// a text item of the local paths, and wins over a file icon image.
+func TestPollClipboard_FileListOnly(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	state := &watchState{lastCount: fake.ChangeCount()}
+
+	uris := "# copied\r\nfile:///home/me/a%20b.txt\r\nfile:///home/me/c.png\r\n"
+	fake.Write(map[ClipFormat][]byte{
+		FormatFiles: []byte(uris),
+		FormatImage: testImage(1),
+	})
+	app.pollClipboard(state)
+
+	if len(app.history) != 1 || app.history[0].Type != TypeText {
+		t.Fatalf("expected one text item, got %+v", app.history)
+	}
+	if got := app.history[0].Text; got != "/home/me/a b.txt\n/home/me/c.png" {
+		t.Errorf("unexpected paths %q", got)
+	}
+	if string(app.history[0].Formats[FormatFiles]) != uris {
+		t.Errorf("expected the file list to be kept")
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// blobStore keeps image bytes on disk as <dir>/<sha256>.png. Each blob is
+// reference-counted by the history file and backups that point at it and is
+// deleted when the last reference is released; gc sweeps files that no
+// reference accounts for (e.g. left behind by a crash). With encryption on,
+// blobs are sealed by crypt; the name stays the hash of the plain image.
+type blobStore struct {
+	dir   string
+	crypt *cryptor // Needed to read or write encrypted blobs
+
+	mu      sync.Mutex
+	refs    map[string]int
+	encrypt bool // Seal new blobs, see setEncrypted
+	modeSet bool // Existing blobs have been brought in line with encrypt
+}
+
+func newBlobStore(dir string) *blobStore {
+	return &blobStore{dir: dir, refs: make(map[string]int)}
+}
+
+// path returns the file holding the blob with the given hash.
+func (s *blobStore) path(hash string) string {
+	return filepath.Join(s.dir, hash+".png")
+}
+
+// acquire adds a reference to a blob that is already stored and reports
+// whether it was; callers fall back to put when it is not.
+func (s *blobStore) acquire(hash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refs[hash] == 0 {
+		if _, err := os.Stat(s.path(hash)); err != nil {
+			return false
+		}
+	}
+	s.refs[hash]++
+	return true
+}
+
+// put stores data under its hash (if not present yet) and adds a reference.
+func (s *blobStore) put(data []byte) (string, error) {
+	hash := hashBytes(data)
+	if hash == "" {
+		return "", errors.New("empty blob")
+	}
+	if s.acquire(hash) {
+		return hash, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", err
+	}
+	if s.encrypt {
+		var err error
+		if data, err = s.crypt.seal(data); err != nil {
+			return "", err
+		}
+	}
+	if err := writeFileAtomic(s.path(hash), data, 0600); err != nil {
+		return "", err
+	}
+	s.refs[hash]++
+	return hash, nil
+}
+
+// get reads the blob with the given hash, decrypting it if it is sealed.
+func (s *blobStore) get(hash string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(hash))
+	if err != nil || !isEncrypted(data) {
+		return data, err
+	}
+	if s.crypt == nil {
+		return nil, errors.New("image is encrypted")
+	}
+	return s.crypt.open(data)
+}
+
+// setEncrypted makes put seal new blobs (or not), and on the first call or
+// a change rewrites every stored blob that is kept the other way.
+func (s *blobStore) setEncrypted(on bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.modeSet && s.encrypt == on {
+		return nil
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".png") {
+			continue
+		}
+		path := filepath.Join(s.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isEncrypted(data) == on {
+			continue
+		}
+		if on {
+			data, err = s.crypt.seal(data)
+		} else {
+			data, err = s.crypt.open(data)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if err := writeFileAtomic(path, data, 0600); err != nil {
+			return err
+		}
+	}
+	s.encrypt, s.modeSet = on, true
+	return nil
+}
+
+// release drops a reference and deletes the blob once nothing refers to it.
+func (s *blobStore) release(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refs[hash] == 0 {
+		return nil
+	}
+	s.refs[hash]--
+	if s.refs[hash] > 0 {
+		return nil
+	}
+	delete(s.refs, hash)
+	if err := os.Remove(s.path(hash)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// gc deletes every blob file without a reference and returns how many it removed.
+func (s *blobStore) gc() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		hash, ok := strings.CutSuffix(entry.Name(), ".png")
+		if !ok || entry.IsDir() || s.refs[hash] > 0 {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, entry.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestBlobStore_PutDedupsByContent verifies equal bytes share one file and one hash.
+func TestBlobStore_PutDedupsByContent(t *testing.T) {
+	s := newBlobStore(t.TempDir())
+	h1, err := s.put([]byte("image bytes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := s.put([]byte("image bytes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 != h2 {
+		t.Errorf("expected same hash, got %q and %q", h1, h2)
+	}
+	if s.refs[h1] != 2 {
+		t.Errorf("expected 2 references, got %d", s.refs[h1])
+	}
+	entries, _ := os.ReadDir(s.dir)
+	if len(entries) != 1 {
+		t.Errorf("expected 1 blob file, got %d", len(entries))
+	}
+}
+
+// TestBlobStore_ReleaseDeletesAtZero verifies a blob survives until its last reference goes.
+func TestBlobStore_ReleaseDeletesAtZero(t *testing.T) {
+	s := newBlobStore(t.TempDir())
+	hash, _ := s.put([]byte("data"))
+	s.put([]byte("data"))
+
+	if err := s.release(hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.get(hash); err != nil {
+		t.Fatalf("blob deleted while still referenced: %v", err)
+	}
+	if err := s.release(hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.path(hash)); !os.IsNotExist(err) {
+		t.Errorf("expected blob to be deleted, stat err = %v", err)
+	}
+}
+
+// TestBlobStore_GCRemovesOrphans verifies unreferenced files are swept and referenced ones kept.
+func TestBlobStore_GCRemovesOrphans(t *testing.T) {
+	dir := t.TempDir()
+	s := newBlobStore(dir)
+	kept, _ := s.put([]byte("kept"))
+	orphan := hashBytes([]byte("orphan"))
+	if err := os.WriteFile(filepath.Join(dir, orphan+".png"), []byte("orphan"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := s.gc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 orphan removed, got %d", n)
+	}
+	if _, err := s.get(kept); err != nil {
+		t.Errorf("referenced blob was removed: %v", err)
+	}
+}
+
+// TestBlobStore_GCMissingDir verifies gc on a store that never wrote anything.
+func TestBlobStore_GCMissingDir(t *testing.T) {
+	s := newBlobStore(filepath.Join(t.TempDir(), "missing"))
+	if n, err := s.gc(); n != 0 || err != nil {
+		t.Errorf("expected (0, nil), got (%d, %v)", n, err)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ContentKind says what a text clip contains, see classify.
+type ContentKind string
+
+const (
+	KindURL    ContentKind = "url"
+	KindEmail  ContentKind = "email"
+	KindPath   ContentKind = "path"
+	KindColor  ContentKind = "color"
+	KindJSON   ContentKind = "json"
+	KindCode   ContentKind = "code"
+	KindPhone  ContentKind = "phone"
+	KindNumber ContentKind = "number" // A number or arithmetic expression
+	KindUUID   ContentKind = "uuid"
+)
+
+var (
+	uuidPattern   = regexp.MustCompile(`^(?i)\{?[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\}?$`)
+	hexColor      = regexp.MustCompile(`^(?i)#([0-9a-f]{3}|[0-9a-f]{4}|[0-9a-f]{6}|[0-9a-f]{8})$`)
+	funcColor     = regexp.MustCompile(`^(?i)(rgba?|hsla?)\(\s*[\d.]+%?\s*(,\s*|\s+)[\d.]+%?\s*(,\s*|\s+)[\d.]+%?\s*([,/]\s*[\d.]+%?\s*)?\)$`)
+	phonePattern  = regexp.MustCompile(`^\+?[\d\s().-]+$`)
+	datePattern   = regexp.MustCompile(`^(\d{4}[-./]\d{1,2}[-./]\d{1,2}|\d{1,2}[-./]\d{1,2}[-./]\d{4})$`)
+	numberPattern = regexp.MustCompile(`^[\d\s.,+\-*/%^()]+$`)
+	unixPath      = regexp.MustCompile(`^(~|\.{1,2})?/[^\x00\n]*$`)
+	windowsPath   = regexp.MustCompile(`^(?i)([a-z]:\\|\\\\)[^\x00\n]*$`)
+)
+
+// classify guesses what text is. For KindCode it also guesses the
+// language; an empty kind means plain text.
+func classify(text string) (ContentKind, string) {
+	text = strings.TrimSpace(text)
+	singleLine := !strings.ContainsAny(text, "\n\r")
+
+	if singleLine {
+		switch {
+		case uuidPattern.MatchString(text):
+			return KindUUID, ""
+		case hexColor.MatchString(text) || funcColor.MatchString(text):
+			return KindColor, ""
+		case isURL(text):
+			return KindURL, ""
+		case isEmail(text):
+			return KindEmail, ""
+		case isPath(text):
+			return KindPath, ""
+		case datePattern.MatchString(text):
+			return "", "" // Neither a phone nor arithmetic
+		case isPhone(text):
+			return KindPhone, ""
+		case isNumber(text):
+			return KindNumber, ""
+		}
+	}
+	if (strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")) && json.Valid([]byte(text)) {
+		return KindJSON, ""
+	}
+	if lang := guessLanguage(text); lang != "" {
+		return KindCode, lang
+	}
+	return "", ""
+}
+
+// isURL reports whether text is a single http(s) URL.
+func isURL(text string) bool {
+	if strings.ContainsFunc(text, unicode.IsSpace) {
+		return false
+	}
+	u, err := url.Parse(text)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isEmail reports whether text is a bare address like a@b.example.
+func isEmail(text string) bool {
+	addr, err := mail.ParseAddress(text)
+	return err == nil && addr.Address == text && strings.Contains(text[strings.LastIndex(text, "@"):], ".")
+}
+
+// isPath reports whether text looks like an absolute or home-relative
+// file path with at least one named component.
+func isPath(text string) bool {
+	if !unixPath.MatchString(text) && !windowsPath.MatchString(text) {
+		return false
+	}
+	return strings.ContainsFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })
+}
+
+// isPhone reports whether text is 7 to 15 digits with phone punctuation,
+// as in "+1 (555) 010-9999". Plain digit runs count as numbers instead, and
+// ranges like "1000-2000" are not phones; classify rules out dates first.
+func isPhone(text string) bool {
+	if !phonePattern.MatchString(text) || !strings.ContainsAny(text, "+ ()-.") {
+		return false
+	}
+	fours := 0
+	for _, group := range strings.Split(text, "-") {
+		if len(group) == 4 && !strings.ContainsFunc(group, func(r rune) bool { return !unicode.IsDigit(r) }) {
+			fours++
+		}
+	}
+	if fours > 1 {
+		return false
+	}
+	digits := 0
+	for _, r := range text {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	if digits < 7 || digits > 15 {
+		return false
+	}
+	// A decimal like "3.14159265" is a number, not a phone.
+	return strings.Count(text, ".") != 1 || strings.ContainsAny(text, " ()-+")
+}
+
+// isNumber reports whether text is a number or an arithmetic expression.
+func isNumber(text string) bool {
+	return numberPattern.MatchString(text) && strings.ContainsFunc(text, unicode.IsDigit)
+}
+
+// languageHints are signals for guessLanguage, checked line by line. A
+// language needs codeThreshold points to be guessed.
+var languageHints = []struct {
+	lang    string
+	pattern *regexp.Regexp
+	weight  int
+}{
+	{"go", regexp.MustCompile(`^package \w+$|^func (\(\w+ \*?\w+\) )?\w+\(|:= |^import \($|\berr != nil\b`), 3},
+	{"python", regexp.MustCompile(`^(def|class) \w+.*:$|^(from \S+ )?import \w+|^\s*(elif|except)\b.*:$|\bself\.|^if __name__ ==`), 3},
+	{"javascript", regexp.MustCompile(`\b(const|let|var) \w+ = |=> |\bfunction\s*\w*\(|console\.log\(|\brequire\(|^export (default )?`), 2},
+	{"typescript", regexp.MustCompile(`^(export )?(interface|type) \w+|: (string|number|boolean)\b|\bas const\b`), 3},
+	{"rust", regexp.MustCompile(`^\s*(pub )?fn \w+|\blet mut\b|^impl\b|^use \w+::|println!\(`), 3},
+	{"java", regexp.MustCompile(`^\s*(public|private|protected) (static )?(final )?(class|void|int|String)\b|System\.out\.print`), 3},
+	{"c", regexp.MustCompile(`^#include [<"]|^int main\(|\bprintf\(|\bmalloc\(`), 3},
+	{"shell", regexp.MustCompile(`^#!/(usr/)?bin/(env )?(ba|z)?sh|^\$ \w|^(sudo|export|echo|cd|apt|brew|npm|go|git) \S|\s&&\s|\|\s*(grep|awk|sed|xargs)\b`), 2},
+	{"sql", regexp.MustCompile(`(?i)^\s*(select .+ from|insert into|update \w+ set|delete from|create table)\b`), 3},
+	{"html", regexp.MustCompile(`(?i)^<!doctype html|</?(html|head|body|div|span|p|a|ul|li|script)\b[^>]*>`), 2},
+	{"css", regexp.MustCompile(`^[.#]?[\w-]+(\s*[,>]?\s*[.#]?[\w-]+)*\s*\{$|^\s*[\w-]+:\s*[^;]+;$`), 2},
+}
+
+// codeThreshold is the score at which text counts as code.
+const codeThreshold = 3
+
+// maxGuessLines bounds how much of a long clip guessLanguage looks at.
+const maxGuessLines = 200
+
+// guessLanguage returns the most likely programming language of text, or
+// "" if it does not look like code.
+func guessLanguage(text string) string {
+	scores := make(map[string]int)
+	lines := strings.SplitN(text, "\n", maxGuessLines+1)
+	for _, line := range lines[:min(len(lines), maxGuessLines)] {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			continue
+		}
+		for _, hint := range languageHints {
+			if hint.pattern.MatchString(line) {
+				scores[hint.lang] += hint.weight
+			}
+		}
+	}
+	best, bestScore := "", 0
+	for _, hint := range languageHints { // Fixed order breaks ties deterministically
+		if s := scores[hint.lang]; s > bestScore {
+			best, bestScore = hint.lang, s
+		}
+	}
+	if bestScore < codeThreshold {
+		return ""
+	}
+	return best
+}
+
+// openTarget returns the URL that opens item: the web page, a new mail to
+// the address, or the file. ok is false for kinds that cannot be opened.
+func openTarget(item ClipItem) (target string, ok bool) {
+	switch item.Kind {
+	case KindURL:
+		return item.Text, true
+	case KindEmail:
+		return "mailto:" + item.Text, true
+	case KindPath:
+		path := item.Text
+		if rest, found := strings.CutPrefix(path, "~/"); found {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", false
+			}
+			path = filepath.Join(home, rest)
+		}
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String(), true
+	}
+	return "", false
+}
+
+// OpenItem opens a URL, email or path clip with the system's default
+// handler. Exported for Wails binding.
+func (a *App) OpenItem(id string) error {
+	a.mu.Lock()
+	index := a.indexOf(id)
+	var item ClipItem
+	if index >= 0 {
+		item = a.history[index]
+	}
+	a.mu.Unlock()
+	if index < 0 {
+		return fmt.Errorf("unknown item %q", id)
+	}
+
+	target, ok := openTarget(item)
+	if !ok {
+		return fmt.Errorf("cannot open %s item", item.Kind)
+	}
+	if a.openURL == nil {
+		return errors.New("opening links is unavailable")
+	}
+	return a.openURL(target)
+}
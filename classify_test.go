@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestClassify verifies each content kind and a few near misses.
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		text string
+		kind ContentKind
+		lang string
+	}{
+		{"https://example.com/a?b=c", KindURL, ""},
+		{"http://localhost:8080", KindURL, ""},
+		{"ftp://example.com", "", ""},
+		{"someone@example.com", KindEmail, ""},
+		{"someone@localhost", "", ""},
+		{"/usr/local/bin/go", KindPath, ""},
+		{"~/Documents/notes.txt", KindPath, ""},
+		{`C:\Users\me\file.txt`, KindPath, ""},
+		{"/", "", ""},
+		{"#ff8800", KindColor, ""},
+		{"#F80", KindColor, ""},
+		{"rgb(255, 136, 0)", KindColor, ""},
+		{"hsla(30 100% 50% / 0.5)", KindColor, ""},
+		{"#ff88", KindColor, ""},
+		{"#xyz", "", ""},
+		{`{"a": [1, 2, {"b": null}]}`, KindJSON, ""},
+		{"[1, 2, 3]", KindJSON, ""},
+		{"{not json}", "", ""},
+		{"+1 (555) 010-9999", KindPhone, ""},
+		{"555-0199 123", KindPhone, ""},
+		{"2024-01-15", "", ""},
+		{"15.01.2024", "", ""},
+		{"2024/1/5", "", ""},
+		{"1000-2000", KindNumber, ""},
+		{"10-20", KindNumber, ""},
+		{"42", KindNumber, ""},
+		{"3.14159265", KindNumber, ""},
+		{"(12 + 30) * 2 / 7", KindNumber, ""},
+		{"123e4567-e89b-12d3-a456-426614174000", KindUUID, ""},
+		{"package main\n\nfunc main() {\n\tx := 1\n}", KindCode, "go"},
+		{"def add(a, b):\n    return a + b", KindCode, "python"},
+		{"const x = () => 1;\nconsole.log(x());", KindCode, "javascript"},
+		{"fn main() {\n    let mut v = 1;\n}", KindCode, "rust"},
+		{"#include <stdio.h>\nint main() { printf(\"hi\"); }", KindCode, "c"},
+		{"SELECT id, name FROM users WHERE id = 1", KindCode, "sql"},
+		{"#!/bin/bash\necho hi && ls | grep x", KindCode, "shell"},
+		{"Hello, world! Nice weather today.", "", ""},
+		{"Meeting notes\n- go over budget\n- plan", "", ""},
+	}
+	for _, tt := range tests {
+		kind, lang := classify(tt.text)
+		if kind != tt.kind || lang != tt.lang {
+			t.Errorf("classify(%q) = (%q, %q), want (%q, %q)", tt.text, kind, lang, tt.kind, tt.lang)
+		}
+	}
+}
+
+// TestAddItem_Classifies verifies kinds are stored on the item and usable
+// as search filters.
+func TestAddItem_Classifies(t *testing.T) {
+	app := &App{}
+	app.addItem("https://example.com")
+	app.addItem(`{"ok": true}`)
+	app.addItem("just words")
+
+	if app.history[2].Kind != KindURL || app.history[1].Kind != KindJSON || app.history[0].Kind != "" {
+		t.Errorf("unexpected kinds: %q %q %q", app.history[2].Kind, app.history[1].Kind, app.history[0].Kind)
+	}
+	results := app.SearchHistory("", SearchFilters{Types: []string{"json", "url"}})
+	if len(results) != 2 {
+		t.Errorf("expected 2 results for json+url, got %d", len(results))
+	}
+}
+
+// TestOpenItem verifies openable kinds map to URLs and others are refused.
+func TestOpenItem(t *testing.T) {
+	app := &App{}
+	var opened []string
+	app.openURL = func(url string) error {
+		opened = append(opened, url)
+		return nil
+	}
+	app.addItem("/tmp/some file.txt")
+	app.addItem("me@example.com")
+	app.addItem("plain")
+
+	for _, item := range app.GetHistory() {
+		err := app.OpenItem(item.ID)
+		if (err != nil) != (item.Kind == "") {
+			t.Errorf("%q: unexpected error state %v", item.Text, err)
+		}
+	}
+	want := "mailto:me@example.com file:///tmp/some%20file.txt"
+	if got := strings.Join(opened, " "); got != want {
+		t.Errorf("opened %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// cliUsage is printed by `clipboard-island help`.
+const cliUsage = `usage: clipboard-island [--json] <command> [args]
+
+Talks to the running instance. Items are addressed by their position as
+the island lists them (1 is the top) or by ID.
+
+  list [n]          List the history, or its first n items
+  get <item>        Print an item's text, or write an image's PNG bytes
+  search <query>    List the items matching query, best first
+  copy [text]       Copy text (stdin if none) to the clipboard and history
+  paste <item>      Paste an item into the focused app
+  pin <item>        Pin an item
+  unpin <item>      Unpin an item
+  delete <item>     Delete an item
+  clear             Delete every unpinned item
+  pause [minutes]   Pause capture, until resumed if no minutes are given
+  resume            Resume capture
+  show              Show the island
+
+--json prints results as JSON instead of plain text.
+`
+
+// previewLength is how many characters of text list and search print.
+const previewLength = 80
+
+// cliItem is how CLI commands report an item. Position addresses it in
+// other commands.
+type cliItem struct {
+	Position int `json:"position"`
+	ClipItem
+}
+
+// resolveItem finds the item ref names: a position as GetHistory lists it
+// (1-based) or an item ID.
+func (a *App) resolveItem(ref string) (cliItem, error) {
+	items := a.GetHistory()
+	if n, err := strconv.Atoi(ref); err == nil && n >= 1 && n <= len(items) {
+		return cliItem{n, items[n-1]}, nil
+	}
+	for i, item := range items {
+		if item.ID == ref {
+			return cliItem{i + 1, item}, nil
+		}
+	}
+	return cliItem{}, fmt.Errorf("no item %q (%d items)", ref, len(items))
+}
+
+// summary drops the image data and rich formats, which list and search
+// leave to get.
+func (item cliItem) summary() cliItem {
+	item.ImageData = ""
+	item.Formats = nil
+	return item
+}
+
+// cliList returns the first limit items of the history, all if limit is 0.
+func (a *App) cliList(limit int) []cliItem {
+	items := a.GetHistory()
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	out := make([]cliItem, len(items))
+	for i, item := range items {
+		out[i] = cliItem{i + 1, item}.summary()
+	}
+	return out
+}
+
+// cliSearch returns the items matching query, best first, with their
+// positions in the history.
+func (a *App) cliSearch(query string) []cliItem {
+	positions := make(map[string]int)
+	for i, item := range a.GetHistory() {
+		positions[item.ID] = i + 1
+	}
+	results := a.SearchHistory(query, SearchFilters{})
+	out := make([]cliItem, len(results))
+	for i, result := range results {
+		out[i] = cliItem{positions[result.Item.ID], result.Item}.summary()
+	}
+	return out
+}
+
+// cliSetPinned pins or unpins the item ref names and returns it.
+func (a *App) cliSetPinned(ref string, pinned bool) (cliItem, error) {
+	item, err := a.resolveItem(ref)
+	if err != nil {
+		return cliItem{}, err
+	}
+	if item.Pinned != pinned {
+		a.TogglePin(item.ID)
+	}
+	return a.resolveItem(item.ID)
+}
+
+// copyText puts text on the clipboard and, unless capture is paused, at
+// the top of the history.
+func (a *App) copyText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("nothing to copy")
+	}
+	if !a.GetPauseStatus().Paused {
+		a.addItem(text)
+	}
+	return a.writeClipboard(map[ClipFormat][]byte{FormatText: []byte(text)})
+}
+
+// cliArgs checks a command got between least and most arguments.
+func cliArgs(args []string, least, most int, usage string) error {
+	if len(args) < least || len(args) > most {
+		return errors.New("usage: " + usage)
+	}
+	return nil
+}
+
+// cliPlain prints each command's result as plain text; commands missing
+// here print nothing.
+var cliPlain = map[string]func(w io.Writer, result json.RawMessage) error{
+	"list":   printItemLines,
+	"search": printItemLines,
+	"get":    printItemContent,
+	"pin":    printItemLine,
+	"unpin":  printItemLine,
+	"clear":  printCleared,
+	"pause":  printPauseStatus,
+	"resume": printPauseStatus,
+}
+
+// wantsCLI reports whether a launch's arguments are a command line for
+// runCLI: a known command or flag first. Others, like the -psn_… argument
+// older macOS adds, start the app as usual.
+func wantsCLI(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "--json", "-j", "--help", "-h", "help":
+		return true
+	}
+	_, ok := new(App).instanceCommands()[args[0]]
+	return ok
+}
+
+// runCLI is main for a launch with arguments: it sends the command to the
+// running instance, prints the result and returns the exit status.
+func runCLI(path string, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	asJSON := false
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "--json", "-j":
+			asJSON = true
+		case "--help", "-h":
+			args = []string{"help"}
+			continue
+		default:
+			fmt.Fprintf(stderr, "clipboard-island: unknown flag %s\n\n%s", args[0], cliUsage)
+			return 2
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 || args[0] == "help" {
+		fmt.Fprint(stdout, cliUsage)
+		return 0
+	}
+	if len(args) == 1 && args[0] == "copy" {
+		text, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, "clipboard-island:", err)
+			return 1
+		}
+		args = append(args, string(text))
+	}
+
+	result, err := forwardCommand(path, args)
+	if err == nil && len(result) > 0 {
+		if format := cliPlain[args[0]]; asJSON || format == nil {
+			var out bytes.Buffer
+			if err = json.Indent(&out, result, "", "  "); err == nil {
+				out.WriteByte('\n')
+				_, err = out.WriteTo(stdout)
+			}
+		} else {
+			err = format(stdout, result)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "clipboard-island:", err)
+		return 1
+	}
+	return 0
+}
+
+// printItemLines prints one tab-separated line per item: position, ID,
+// kind, pinned and a preview.
+func printItemLines(w io.Writer, result json.RawMessage) error {
+	var items []cliItem
+	if err := json.Unmarshal(result, &items); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if _, err := fmt.Fprintln(w, itemLine(item)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printItemLine prints a single item like printItemLines.
+func printItemLine(w io.Writer, result json.RawMessage) error {
+	var item cliItem
+	if err := json.Unmarshal(result, &item); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w, itemLine(item))
+	return err
+}
+
+// itemLine formats an item for printItemLines.
+func itemLine(item cliItem) string {
+	kind := string(item.Type)
+	if item.Kind != "" {
+		kind = string(item.Kind)
+	}
+	pinned := "-"
+	if item.Pinned {
+		pinned = "pinned"
+	}
+	return fmt.Sprintf("%d\t%s\t%s\t%s\t%s", item.Position, item.ID, kind, pinned, preview(item))
+}
+
+// preview is the first line of an item's text, shortened to previewLength,
+// with tabs turned into spaces to keep the columns intact.
+func preview(item cliItem) string {
+	if item.Type == TypeImage {
+		return "[image]"
+	}
+	text, rest, multiline := strings.Cut(strings.ReplaceAll(item.Text, "\t", " "), "\n")
+	if utf8.RuneCountInString(text) > previewLength {
+		text = string([]rune(text)[:previewLength-1]) + "…"
+	} else if multiline && rest != "" {
+		text += " …"
+	}
+	return text
+}
+
+// printItemContent writes an item's text as is, or its image as PNG bytes.
+func printItemContent(w io.Writer, result json.RawMessage) error {
+	var item cliItem
+	if err := json.Unmarshal(result, &item); err != nil {
+		return err
+	}
+	if item.Type == TypeImage {
+		data, err := decodeBase64(item.ImageData)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	_, err := io.WriteString(w, item.Text)
+	return err
+}
+
+// printCleared reports how many items clear removed.
+func printCleared(w io.Writer, result json.RawMessage) error {
+	var removed int
+	if err := json.Unmarshal(result, &removed); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "Removed %d items\n", removed)
+	return err
+}
+
+// printPauseStatus describes the pause state after pause or resume.
+func printPauseStatus(w io.Writer, result json.RawMessage) error {
+	var status PauseStatus
+	if err := json.Unmarshal(result, &status); err != nil {
+		return err
+	}
+	var err error
+	switch {
+	case !status.Paused:
+		_, err = fmt.Fprintln(w, "Capture resumed")
+	case status.Until.IsZero():
+		_, err = fmt.Fprintln(w, "Capture paused until resumed")
+	default:
+		_, err = fmt.Fprintf(w, "Capture paused until %s\n", status.Until.Local().Format(time.Kitchen))
+	}
+	return err
+}
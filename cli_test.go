@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// runTestCLI runs the CLI against the instance at path and returns its
+// exit status and output.
+func runTestCLI(t *testing.T, path, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := runCLI(path, args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// TestCLI_ManagesHistory verifies list, get, pin, unpin, delete and clear
+// against a running instance, in plain and JSON output.
+func TestCLI_ManagesHistory(t *testing.T) {
+	app := NewApp(newFakeBackend())
+	app.addItem("alpha")
+	app.addItem("beta\twith a tab\nand a second line")
+	app.addItem("gamma")
+	path := serveTestInstance(t, app)
+
+	code, out, _ := runTestCLI(t, path, "", "list")
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if code != 0 || len(lines) != 3 {
+		t.Fatalf("list: exit %d, output %q", code, out)
+	}
+	if fields := strings.Split(lines[1], "\t"); len(fields) != 5 || fields[0] != "2" || fields[4] != "beta with a tab …" {
+		t.Errorf("unexpected list line %q", lines[1])
+	}
+
+	code, out, _ = runTestCLI(t, path, "", "--json", "list", "2")
+	var items []cliItem
+	if err := json.Unmarshal([]byte(out), &items); code != 0 || err != nil || len(items) != 2 || items[0].Text != "gamma" {
+		t.Fatalf("list --json: exit %d, %v, %q", code, err, out)
+	}
+
+	if code, out, _ = runTestCLI(t, path, "", "get", "3"); code != 0 || out != "alpha" {
+		t.Errorf("get 3: exit %d, output %q", code, out)
+	}
+	if code, out, _ = runTestCLI(t, path, "", "get", items[1].ID); code != 0 || !strings.HasPrefix(out, "beta") {
+		t.Errorf("get by ID: exit %d, output %q", code, out)
+	}
+
+	if code, out, _ = runTestCLI(t, path, "", "pin", "3"); code != 0 || !strings.Contains(out, "\tpinned\t") {
+		t.Errorf("pin: exit %d, output %q", code, out)
+	}
+	if code, _, _ = runTestCLI(t, path, "", "delete", "1"); code != 0 {
+		t.Errorf("delete: exit %d", code)
+	}
+	if code, out, _ = runTestCLI(t, path, "", "clear"); code != 0 || out != "Removed 1 items\n" {
+		t.Errorf("clear: exit %d, output %q", code, out)
+	}
+	if h := app.GetHistory(); len(h) != 1 || h[0].Text != "alpha" || !h[0].Pinned {
+		t.Fatalf("expected only the pinned 'alpha' left, got %+v", h)
+	}
+	if code, out, _ = runTestCLI(t, path, "", "unpin", "1"); code != 0 || !strings.Contains(out, "\t-\t") {
+		t.Errorf("unpin: exit %d, output %q", code, out)
+	}
+}
+
+// TestCLI_CopyAndSearch verifies copy takes text from stdin onto the
+// clipboard and into the history, and search finds it.
+func TestCLI_CopyAndSearch(t *testing.T) {
+	clip := newFakeBackend()
+	app := NewApp(clip)
+	app.addItem("unrelated")
+	path := serveTestInstance(t, app)
+
+	if code, _, errOut := runTestCLI(t, path, "from a script\n", "copy"); code != 0 {
+		t.Fatalf("copy: exit %d, %s", code, errOut)
+	}
+	if got := string(clip.Read(FormatText)); got != "from a script\n" {
+		t.Errorf("clipboard holds %q", got)
+	}
+	if h := app.GetHistory(); len(h) != 2 || h[0].Text != "from a script" {
+		t.Errorf("expected the copy at the top of the history, got %+v", h)
+	}
+
+	code, out, _ := runTestCLI(t, path, "", "search", "scrpt")
+	if code != 0 || !strings.HasPrefix(out, "1\t") || strings.Count(out, "\n") != 1 {
+		t.Errorf("search: exit %d, output %q", code, out)
+	}
+}
+
+// TestCLI_Pause verifies pause and resume report the new state.
+func TestCLI_Pause(t *testing.T) {
+	app := NewApp(newFakeBackend())
+	path := serveTestInstance(t, app)
+
+	if code, out, _ := runTestCLI(t, path, "", "pause", "10"); code != 0 || !strings.HasPrefix(out, "Capture paused until ") {
+		t.Errorf("pause 10: exit %d, output %q", code, out)
+	}
+	if status := app.GetPauseStatus(); !status.Paused || status.Until.IsZero() {
+		t.Errorf("expected a timed pause, got %+v", status)
+	}
+	code, out, _ := runTestCLI(t, path, "", "-j", "resume")
+	var status PauseStatus
+	if err := json.Unmarshal([]byte(out), &status); code != 0 || err != nil || status.Paused {
+		t.Errorf("resume --json: exit %d, %v, %q", code, err, out)
+	}
+}
+
+// TestCLI_Errors verifies bad input and a missing instance fail with a
+// message and a non-zero status.
+func TestCLI_Errors(t *testing.T) {
+	app := NewApp(newFakeBackend())
+	path := serveTestInstance(t, app)
+
+	for _, args := range [][]string{{"get", "1"}, {"pin"}, {"list", "0"}, {"pause", "soon"}, {"frobnicate"}, {"copy"}} {
+		if code, _, errOut := runTestCLI(t, path, "", args...); code != 1 || errOut == "" {
+			t.Errorf("%v: exit %d, stderr %q", args, code, errOut)
+		}
+	}
+	if code, _, _ := runTestCLI(t, path, "", "--verbose", "list"); code != 2 {
+		t.Errorf("unknown flag: exit %d, want 2", code)
+	}
+	if code, out, _ := runTestCLI(t, path, "", "help"); code != 0 || !strings.Contains(out, "usage:") {
+		t.Errorf("help: exit %d, output %q", code, out)
+	}
+	if code, _, errOut := runTestCLI(t, testSocketPath(t), "", "list"); code != 1 || !strings.Contains(errOut, "not running") {
+		t.Errorf("no instance: exit %d, stderr %q", code, errOut)
+	}
+}
+
+// TestWantsCLI verifies only known commands and flags make a launch a CLI
+// call, so arguments the OS adds still start the app.
+func TestWantsCLI(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"list"}, true},
+		{[]string{"paste", "2"}, true},
+		{[]string{"--json", "list"}, true},
+		{[]string{"-h"}, true},
+		{[]string{"help"}, true},
+		{[]string{"-psn_0_12345"}, false},
+		{[]string{"frobnicate"}, false},
+	}
+	for _, tt := range tests {
+		if got := wantsCLI(tt.args); got != tt.want {
+			t.Errorf("wantsCLI(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -2,19 +2,20 @@ package main
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
-	"encoding/json"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"image"
 	"image/png"
 	"log"
-	"os"
-	"os/exec"
-	"path/filepath"
+	"net/url"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/adrg/xdg"
-	"golang.design/x/clipboard"
 	"golang.org/x/image/draw"
 )
 
@@ -33,8 +34,8 @@ func decodeBase64(dataURI string) ([]byte, error) {
 }
 
 // resizeImage scales down large images to reduce memory usage.
-// Max dimension is 1200px (maintains aspect ratio).
-func resizeImage(data []byte) ([]byte, error) {
+// The longer side is capped at maxDim pixels (maintains aspect ratio).
+func resizeImage(data []byte, maxDim int) ([]byte, error) {
 	// Decode image
 	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
@@ -47,8 +48,7 @@ func resizeImage(data []byte) ([]byte, error) {
 	height := bounds.Dy()
 
 	// If already small enough, return as-is
-	// Using 1200px max to keep text readable in screenshots (~30% file size reduction)
-	maxDim := 1200
+	// The 1200px default keeps text readable in screenshots (~30% file size reduction)
 	if width <= maxDim && height <= maxDim {
 		return data, nil
 	}
@@ -86,126 +86,132 @@ const (
 
 // ClipItem represents a single clipboard item.
 type ClipItem struct {
-	Type      ClipItemType `json:"type"`
-	Text      string       `json:"text,omitempty"`
-	ImageData string       `json:"imageData,omitempty"` // Base64 encoded image
-	Pinned    bool         `json:"pinned"`
+	ID         string       `json:"id"` // Stable across reordering; the frontend addresses items by ID
+	Type       ClipItemType `json:"type"`
+	Text       string       `json:"text,omitempty"`
+	ImageData  string       `json:"imageData,omitempty"` // Base64 encoded image
+	ImageHash  string       `json:"imageHash,omitempty"` // SHA-256 of the image bytes, see blobStore
+	Pinned     bool         `json:"pinned"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	LastUsedAt time.Time    `json:"lastUsedAt"` // Last copied or pasted
+	UseCount   int          `json:"useCount"`   // Times pasted or copied again, see frecency
+
+	// Kind classifies text clips (URL, JSON, code, ...); Language is the
+	// guessed programming language for KindCode. See classify.
+	Kind     ContentKind `json:"kind,omitempty"`
+	Language string      `json:"language,omitempty"`
+
+	// Formats holds rich representations of a text clip (HTML, RTF, file
+	// list) captured with it and restored together on paste.
+	Formats map[ClipFormat][]byte `json:"formats,omitempty"`
+
+	// Sensitive marks text that looks like a secret of SecretKind, see
+	// detectSecret. Such items are masked (Masked is set on the copies
+	// clients get) until revealed, expire at ExpiresAt even if pinned, and
+	// are never saved to disk.
+	Sensitive  bool      `json:"sensitive,omitempty"`
+	SecretKind string    `json:"secretKind,omitempty"`
+	ExpiresAt  time.Time `json:"expiresAt,omitzero"`
+	Masked     bool      `json:"masked,omitempty"`
+	revealed   bool
 }
 
-// initClipboard initializes the clipboard package.
-func (a *App) initClipboard() error {
-	return clipboard.Init()
+// newItemID returns a random 64-bit hex identifier for a ClipItem.
+func newItemID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	return hex.EncodeToString(b[:])
 }
 
-// watchClipboard polls the clipboard for changes and captures content.
-// Uses adaptive polling: faster when active, slower when idle to save CPU.
-func (a *App) watchClipboard() {
-	log.Println("[clipboard] Starting clipboard watcher...")
-
-	// Initial change count
-	lastCount := getPasteboardChangeCount()
-	lastText := ""
-	lastImageHash := ""
-	idleTicks := 0
-
-	for {
-		// Adaptive sleep: 200ms when active, 1000ms when idle (5+ ticks no change)
-		if idleTicks < 25 { // ~5 seconds of inactivity
-			time.Sleep(200 * time.Millisecond)
-		} else {
-			time.Sleep(1000 * time.Millisecond) // Idle mode: check once per second
-		}
-
-		currentCount := getPasteboardChangeCount()
-		if currentCount == lastCount {
-			idleTicks++
-			continue
-		}
-
-		// Activity detected, reset idle counter
-		idleTicks = 0
-
-		// Check if we recently pasted (within last 500ms) - skip to avoid capturing our own paste
-		a.mu.Lock()
-		timeSincePaste := time.Since(a.lastPasteTime)
-		skipChange := currentCount == a.lastChangeCount || timeSincePaste < 500*time.Millisecond
-		a.mu.Unlock()
+// newClipItem creates an item of the given type with a fresh ID and timestamps.
+func newClipItem(typ ClipItemType) ClipItem {
+	now := time.Now()
+	return ClipItem{ID: newItemID(), Type: typ, CreatedAt: now, LastUsedAt: now}
+}
 
-		if skipChange {
-			// This is our own paste, skip it
-			lastCount = currentCount
-			continue
+// indexOf returns the position of the item with the given ID, or -1.
+// Caller must hold a.mu.
+func (a *App) indexOf(id string) int {
+	for i, item := range a.history {
+		if item.ID == id {
+			return i
 		}
+	}
+	return -1
+}
 
-		// Try reading image first
-		imgData := clipboard.Read(clipboard.FmtImage)
-		if len(imgData) > 0 {
-			// Simple hash check for duplicates
-			hash := hashBytes(imgData)
-			if hash != lastImageHash {
-				lastImageHash = hash
-				lastCount = currentCount
-				a.addImageItem(imgData)
-				log.Printf("[clipboard] Captured image (%d bytes)", len(imgData))
-			} else {
-				lastCount = currentCount
-			}
+// filePaths turns a text/uri-list into one local path per line, skipping
+// comments and anything that is not a file:// URL.
+func filePaths(uriList []byte) string {
+	var paths []string
+	for _, line := range strings.Split(string(uriList), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-
-		// No image, try text
-		data := clipboard.Read(clipboard.FmtText)
-		if len(data) == 0 {
-			lastCount = currentCount
+		u, err := url.Parse(line)
+		if err != nil || u.Scheme != "file" {
 			continue
 		}
-
-		text := string(data)
-
-		// Skip if same as last captured text (prevents duplicates from rapid polling)
-		if text == lastText {
-			lastCount = currentCount
-			continue
-		}
-
-		lastText = text
-		lastCount = currentCount
-
-		a.addItem(text)
-		log.Printf("[clipboard] Captured %d chars", len(text))
+		paths = append(paths, u.Path)
 	}
+	return strings.Join(paths, "\n")
 }
 
-// hashBytes creates a simple hash for byte comparison.
+// hashBytes returns the hex SHA-256 of data, or "" for empty data.
+// It identifies images for dedup and names their blobs on disk.
 func hashBytes(data []byte) string {
 	if len(data) == 0 {
 		return ""
 	}
-	// Use first 16 bytes + length as simple hash
-	n := 16
-	if len(data) < n {
-		n = len(data)
-	}
-	return string(data[:n]) + "_" + string(rune(len(data)))
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
-// addItem adds a new text item to the clipboard history.
-// It prepends to the front, dedups (moves to top), caps at 30, and skips empty/whitespace.
-// It also skips items that match lastWritten (to avoid re-capturing pasted content).
-// If same text exists and is pinned, the new item is skipped (don't re-add).
+// addItem adds a new plain text item to the clipboard history, see addTextItem.
 func (a *App) addItem(text string) {
+	a.addTextItem(text, nil)
+}
+
+// addTextItem adds a new text item, with any rich formats, to the clipboard history.
+// It prepends to the front, dedups (moves to top), enforces the history limits,
+// and skips empty/whitespace or text longer than Settings.MaxTextLength; rich
+// formats over that length are dropped.
+// If same text exists and is pinned, the new item is skipped (don't re-add).
+func (a *App) addTextItem(text string, formats map[ClipFormat][]byte) {
 	text = strings.TrimSpace(text)
 	if text == "" {
 		return
 	}
-
+	settings := a.GetSettings()
+	if len(text) > settings.MaxTextLength {
+		log.Printf("[clipboard] Skipped %d chars (over maxTextLength %d)", len(text), settings.MaxTextLength)
+		return
+	}
+	kind, language := classify(text) // Before locking; regexes over long text take a while
+	secret := detectSecret(text)
 	a.mu.Lock()
-	defer a.mu.Unlock()
 
-	// Skip if this is the text we just wrote (from SelectItem)
-	if text == a.lastWritten {
-		a.lastWritten = "" // Clear after one skip
-		return
+	prevIDs := a.historyIDs()
+	newItem := newClipItem(TypeText)
+	newItem.Text = text
+	newItem.Kind, newItem.Language = kind, language
+	if secret != "" {
+		newItem.Sensitive, newItem.SecretKind = true, secret
+		newItem.ExpiresAt = newItem.CreatedAt.Add(time.Duration(settings.SensitiveTTLSeconds) * time.Second)
+		log.Printf("[clipboard] Clip looks like a %s, masking it", secret)
+	}
+	for format, data := range formats {
+		if len(data) > settings.MaxTextLength {
+			log.Printf("[clipboard] Dropped %s (%d bytes, over maxTextLength)", format, len(data))
+			continue
+		}
+		if newItem.Formats == nil {
+			newItem.Formats = make(map[ClipFormat][]byte)
+		}
+		newItem.Formats[format] = data
 	}
 
 	// Check for duplicates
@@ -213,33 +219,40 @@ func (a *App) addItem(text string) {
 		if item.Type == TypeText && item.Text == text {
 			// If existing item is pinned, skip the new addition entirely
 			if item.Pinned {
+				a.mu.Unlock()
 				return
 			}
-			// Remove existing non-pinned item (will be re-added at front)
+			// Remove existing non-pinned item (will be re-added at front, keeping its identity)
+			newItem.ID, newItem.CreatedAt, newItem.UseCount = item.ID, item.CreatedAt, item.UseCount+1
 			a.history = append(a.history[:i], a.history[i+1:]...)
 			break
 		}
 	}
 
 	// Add new item at the front
-	newItem := ClipItem{Type: TypeText, Text: text, Pinned: false}
 	a.history = append([]ClipItem{newItem}, a.history...)
 
-	// Cap at 30 items, but preserve pinned items
-	if len(a.history) > 30 {
-		a.history = a.trimToCap()
+	// Enforce history limits, but preserve pinned items
+	a.capHistory()
+	change := a.historyChanged(prevIDs, newItem)
+	a.mu.Unlock()
+
+	a.publish(change)
+	if settings.PersistHistory {
+		a.scheduleSave()
 	}
 }
 
 // addImageItem adds an image to the clipboard history.
-// Images are resized to max 1200px to reduce memory usage while keeping text readable.
+// Images are resized to Settings.ImageMaxDimension to reduce memory usage while keeping text readable.
 func (a *App) addImageItem(imgData []byte) {
 	if len(imgData) == 0 {
 		return
 	}
 
-	// Resize image to reduce memory (max 1200px dimension)
-	resizedData, err := resizeImage(imgData)
+	// Resize image to reduce memory
+	settings := a.GetSettings()
+	resizedData, err := resizeImage(imgData, settings.ImageMaxDimension)
 	if err != nil {
 		log.Printf("[clipboard] failed to resize image: %v", err)
 		resizedData = imgData // Fallback to original
@@ -249,35 +262,34 @@ func (a *App) addImageItem(imgData []byte) {
 	hash := hashBytes(resizedData)
 
 	a.mu.Lock()
-	defer a.mu.Unlock()
 
-	// Skip if this image matches lastWritten (prevents re-capturing pasted images)
-	if hash == hashBytes([]byte(a.lastWritten)) {
-		a.lastWritten = ""
-		return
-	}
+	prevIDs := a.historyIDs()
 
 	// Encode resized image to base64 for storage
 	imgBase64 := "data:image/png;base64," + encodeBase64(resizedData)
 
+	newItem := newClipItem(TypeImage)
+	newItem.ImageData = imgBase64
+	newItem.ImageHash = hash
+
 	// Check for duplicate images (compare by hash of resized data)
-	for i, item := range a.history {
-		if item.Type == TypeImage {
-			// Decode stored image and hash it for comparison
-			storedData, _ := decodeBase64(item.ImageData)
-			if hashBytes(storedData) == hash {
-				if item.Pinned {
-					return
-				}
-				a.history = append(a.history[:i], a.history[i+1:]...)
-				break
+	if id, ok := a.imageIndex[hash]; ok {
+		if i := a.indexOf(id); i >= 0 {
+			if a.history[i].Pinned {
+				a.mu.Unlock()
+				return
 			}
+			newItem.ID, newItem.CreatedAt, newItem.UseCount = id, a.history[i].CreatedAt, a.history[i].UseCount+1
+			a.history = append(a.history[:i], a.history[i+1:]...)
 		}
 	}
 
 	// Add new image item at the front
-	newItem := ClipItem{Type: TypeImage, ImageData: imgBase64, Pinned: false}
 	a.history = append([]ClipItem{newItem}, a.history...)
+	if a.imageIndex == nil {
+		a.imageIndex = make(map[string]string)
+	}
+	a.imageIndex[hash] = newItem.ID
 
 	// Log size reduction
 	originalKB := len(imgData) / 1024
@@ -286,209 +298,278 @@ func (a *App) addImageItem(imgData []byte) {
 		log.Printf("[clipboard] Image resized: %dKB → %dKB", originalKB, resizedKB)
 	}
 
-	// Cap at 30 items
-	if len(a.history) > 30 {
-		a.history = a.trimToCap()
+	// Enforce history limits
+	a.capHistory()
+	change := a.historyChanged(prevIDs, newItem)
+	a.mu.Unlock()
+
+	a.publish(change)
+	if settings.PersistHistory {
+		a.scheduleSave()
 	}
 }
 
-// trimToCap reduces history to 30 items while preserving pinned items.
-// It removes oldest non-pinned items first.
-func (a *App) trimToCap() []ClipItem {
-	if len(a.history) <= 30 {
-		return a.history
+// capHistory applies trimToCap and forgets evicted images in imageIndex.
+// Caller must hold a.mu.
+func (a *App) capHistory() {
+	kept := a.trimToCap()
+	if len(kept) == len(a.history) {
+		return
+	}
+	keptIDs := make(map[string]bool, len(kept))
+	for _, item := range kept {
+		keptIDs[item.ID] = true
 	}
+	for _, item := range a.history {
+		if item.Type == TypeImage && !keptIDs[item.ID] {
+			delete(a.imageIndex, item.ImageHash)
+		}
+	}
+	a.history = kept
+}
 
-	var result []ClipItem
-	var nonPinned []ClipItem
+// trimToCap is the eviction policy: it returns history reduced to the
+// item-count, image-count and total-size limits from settings, keeping
+// order. Pinned items are never evicted but count toward every limit;
+// unpinned items are kept newest first while they still fit, so one large
+// image over budget does not push out smaller text clips behind it.
+func (a *App) trimToCap() []ClipItem {
+	limits := a.settings.withDefaults()
 
-	// Separate pinned and non-pinned
+	count, images, size := 0, 0, 0
 	for _, item := range a.history {
 		if item.Pinned {
-			result = append(result, item)
-		} else {
-			nonPinned = append(nonPinned, item)
+			count++
+			size += itemSize(item)
+			if item.Type == TypeImage {
+				images++
+			}
 		}
 	}
 
-	// Add non-pinned items from the front until we hit the cap
-	spaceForNonPinned := 30 - len(result)
-	if spaceForNonPinned > 0 && len(nonPinned) > 0 {
-		if len(nonPinned) > spaceForNonPinned {
-			nonPinned = nonPinned[:spaceForNonPinned]
+	var result []ClipItem
+	for _, item := range a.history {
+		if !item.Pinned {
+			isImage := item.Type == TypeImage
+			if count >= limits.MaxItems ||
+				(isImage && images >= limits.MaxImages) ||
+				size+itemSize(item) > limits.MaxTotalBytes {
+				continue
+			}
+			count++
+			size += itemSize(item)
+			if isImage {
+				images++
+			}
 		}
-		result = append(nonPinned, result...)
+		result = append(result, item)
 	}
-
 	return result
 }
 
-// GetHistory returns a copy of the clipboard history.
+// itemSize is the memory an item's content takes, as counted against
+// Settings.MaxTotalBytes.
+func itemSize(item ClipItem) int {
+	size := len(item.Text) + len(item.ImageData)
+	for _, data := range item.Formats {
+		size += len(data)
+	}
+	return size
+}
+
+// GetHistory returns a copy of the clipboard history, in the order set by
+// Settings.SortMode.
 // This is exported for Wails binding.
 func (a *App) GetHistory() []ClipItem {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	result := make([]ClipItem, len(a.history))
-	copy(result, a.history)
-	return result
+	items := a.displayHistory()
+	maskItems(items)
+	return items
 }
 
-// SelectItem selects an item from history, copies it to clipboard, hides the window,
-// restores focus to the previous app, and simulates paste.
-func (a *App) SelectItem(index int) {
+// SelectItem selects an item from history by ID, copies it to clipboard, hides the window,
+// restores focus to the previous app, and simulates paste. It pastes in the
+// default Settings.PasteMode; see PasteItemAs for the others.
+func (a *App) SelectItem(id string) error {
+	return a.pasteItem(id, a.GetSettings().PasteMode, nil)
+}
+
+// pasteItem implements SelectItem, PasteItemAs and PasteTransformed: it
+// pastes the item in mode, running text through transformIDs first. If the
+// clipboard cannot be written it returns the error and sends no keystroke,
+// so the user's previous clipboard is not pasted in its place.
+func (a *App) pasteItem(id string, mode PasteMode, transformIDs []string) error {
 	a.mu.Lock()
-	if index < 0 || index >= len(a.history) {
+	index := a.indexOf(id)
+	if index < 0 {
 		a.mu.Unlock()
-		log.Printf("[clipboard] SelectItem: invalid index %d", index)
-		return
+		return fmt.Errorf("unknown id %q", id)
 	}
 	item := a.history[index]
 
-	// Pre-compute lastWritten BEFORE writing to clipboard to avoid race condition
-	var writeData []byte
+	writeData := make(map[ClipFormat][]byte)
 	if item.Type == TypeImage {
+		if len(transformIDs) > 0 {
+			a.mu.Unlock()
+			return errors.New("transforms apply to text only")
+		}
 		imgData, err := decodeBase64(item.ImageData)
 		if err != nil {
 			a.mu.Unlock()
-			log.Printf("[clipboard] failed to decode image: %v", err)
-			return
+			return fmt.Errorf("failed to decode image: %w", err)
 		}
-		writeData = imgData
-		a.lastWritten = hashBytes(imgData)
+		writeData[FormatImage] = imgData
 	} else {
-		writeData = []byte(item.Text)
-		a.lastWritten = item.Text
+		text, formats := pasteText(item, mode)
+		if len(transformIDs) > 0 {
+			var err error
+			if text, err = applyTransforms(text, transformIDs); err != nil {
+				a.mu.Unlock()
+				return err
+			}
+			formats = nil
+		}
+		writeData[FormatText] = []byte(text)
+		for format, data := range formats {
+			writeData[format] = data
+		}
+		if item.Sensitive {
+			writeData[FormatConcealed] = []byte("secret") // Keep it out of other clipboard managers
+		}
 	}
 
-	// Update change count and paste time BEFORE writing to clipboard
-	a.lastChangeCount = getPasteboardChangeCount()
-	a.lastPasteTime = time.Now()
 	a.mu.Unlock()
 
-	// Now write to clipboard (after lastWritten and lastChangeCount are set)
-	if item.Type == TypeImage {
-		clipboard.Write(clipboard.FmtImage, writeData)
-	} else {
-		clipboard.Write(clipboard.FmtText, writeData)
+	if err := a.writeClipboard(writeData); err != nil {
+		return fmt.Errorf("copy to clipboard: %w", err)
+	}
+
+	// Record the use, which may reorder the history in frecency mode
+	a.mu.Lock()
+	var change HistoryChange
+	save := false
+	if index = a.indexOf(id); index >= 0 {
+		a.history[index].LastUsedAt = time.Now()
+		a.history[index].UseCount++
+		change = a.historyChanged(nil, a.history[index])
+		save = a.history[index].Pinned || a.settings.PersistHistory
+	}
+	a.mu.Unlock()
+
+	if index >= 0 {
+		a.publish(change)
+	}
+	if save {
+		a.scheduleSave()
 	}
 
 	// Hide window
-	a.window.Hide()
+	if a.window != nil {
+		a.window.Hide()
+	}
 
 	// Restore focus and paste in background
 	go func() {
 		time.Sleep(50 * time.Millisecond)
 		a.restorePreviousApp()
 		time.Sleep(100 * time.Millisecond)
-		simulatePaste()
+		if err := a.clip.Paste(); err != nil {
+			log.Printf("[clipboard] simulatePaste failed: %v", err)
+		}
 	}()
+	return nil
 }
 
-// simulatePaste simulates Cmd+V keystroke using AppleScript.
-func simulatePaste() {
-	script := `tell application "System Events" to keystroke "v" using command down`
-	if err := exec.Command("osascript", "-e", script).Run(); err != nil {
-		log.Printf("[clipboard] simulatePaste failed: %v", err)
-	}
-}
-
-// getHistoryFilePath returns the path to the history file.
-func getHistoryFilePath() string {
-	return filepath.Join(xdg.DataHome, "clipboard-island", "history.json")
-}
-
-// savePinned writes only pinned items to disk as JSON.
-func (a *App) savePinned() {
+// pastePrevious pastes the second most recent item into the focused app,
+// without showing the island. Bound to Settings.PastePreviousHotkey.
+func (a *App) pastePrevious() {
 	a.mu.Lock()
-	var pinned []ClipItem
-	for _, item := range a.history {
-		if item.Pinned {
-			pinned = append(pinned, item)
-		}
-	}
-	a.mu.Unlock()
-
-	// Create directory if needed
-	path := getHistoryFilePath()
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		log.Printf("[clipboard] failed to create data directory: %v", err)
-		return
-	}
-
-	// Write to file
-	data, err := json.MarshalIndent(pinned, "", "  ")
-	if err != nil {
-		log.Printf("[clipboard] failed to marshal history: %v", err)
+	if len(a.history) < 2 {
+		a.mu.Unlock()
 		return
 	}
+	id := a.history[1].ID
+	a.mu.Unlock()
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		log.Printf("[clipboard] failed to write history file: %v", err)
+	a.capturePreviousApp()
+	if err := a.SelectItem(id); err != nil {
+		log.Printf("[clipboard] paste previous: %v", err)
 	}
 }
 
-// loadHistory reads pinned items from disk on startup.
-// All loaded items are marked as Pinned = true.
-func (a *App) loadHistory() {
-	path := getHistoryFilePath()
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			log.Printf("[clipboard] failed to read history file: %v", err)
-		}
-		return
-	}
-
-	var pinned []ClipItem
-	if err := json.Unmarshal(data, &pinned); err != nil {
-		log.Printf("[clipboard] failed to unmarshal history: %v", err)
-		return
-	}
-
+// TogglePin toggles the pinned state of the item with the given ID.
+// Exported for Wails binding.
+func (a *App) TogglePin(id string) {
 	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	// Add pinned items to history (marked as pinned)
-	for _, item := range pinned {
-		if item.Text != "" {
-			item.Pinned = true
-			a.history = append(a.history, item)
-		}
+	index := a.indexOf(id)
+	if index < 0 {
+		a.mu.Unlock()
+		log.Printf("[clipboard] TogglePin: unknown id %q", id)
+		return
 	}
+	a.history[index].Pinned = !a.history[index].Pinned
+	change := a.historyChanged(nil, a.history[index])
+	a.mu.Unlock()
 
-	log.Printf("[clipboard] Loaded %d pinned items", len(pinned))
+	a.publish(change)
+	a.scheduleSave()
 }
 
-// TogglePin toggles the pinned state of an item at the given index.
+// DeleteItem removes the item with the given ID from history.
 // Exported for Wails binding.
-func (a *App) TogglePin(index int) {
+func (a *App) DeleteItem(id string) {
 	a.mu.Lock()
-	if index < 0 || index >= len(a.history) {
+	index := a.indexOf(id)
+	if index < 0 {
 		a.mu.Unlock()
-		log.Printf("[clipboard] TogglePin: invalid index %d", index)
+		log.Printf("[clipboard] DeleteItem: unknown id %q", id)
 		return
 	}
-	a.history[index].Pinned = !a.history[index].Pinned
+	wasPinned := a.history[index].Pinned
+	persistAll := a.settings.PersistHistory
+	if a.history[index].Type == TypeImage {
+		delete(a.imageIndex, a.history[index].ImageHash)
+	}
+	a.history = append(a.history[:index], a.history[index+1:]...)
+	change := a.historyChanged([]string{id})
 	a.mu.Unlock()
 
-	a.savePinned()
+	a.publish(change)
+
+	if wasPinned || persistAll {
+		a.scheduleSave()
+	}
 }
 
-// DeleteItem removes an item from history at the given index.
+// ClearHistory removes every unpinned item and returns how many it removed.
 // Exported for Wails binding.
-func (a *App) DeleteItem(index int) {
+func (a *App) ClearHistory() int {
 	a.mu.Lock()
-	if index < 0 || index >= len(a.history) {
+	prevIDs := a.historyIDs()
+	a.history = slices.DeleteFunc(a.history, func(item ClipItem) bool {
+		if item.Pinned {
+			return false
+		}
+		if item.Type == TypeImage {
+			delete(a.imageIndex, item.ImageHash)
+		}
+		return true
+	})
+	removed := len(prevIDs) - len(a.history)
+	if removed == 0 {
 		a.mu.Unlock()
-		log.Printf("[clipboard] DeleteItem: invalid index %d", index)
-		return
+		return 0
 	}
-	wasPinned := a.history[index].Pinned
-	a.history = append(a.history[:index], a.history[index+1:]...)
+	persistAll := a.settings.PersistHistory
+	change := a.historyChanged(prevIDs)
 	a.mu.Unlock()
 
-	if wasPinned {
-		a.savePinned()
+	log.Printf("[clipboard] Cleared %d items", removed)
+	a.publish(change)
+	if persistAll {
+		a.scheduleSave()
 	}
+	return removed
 }
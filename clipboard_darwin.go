@@ -4,15 +4,189 @@ package main
 #cgo CFLAGS: -x objective-c
 #cgo LDFLAGS: -framework Cocoa
 #import <Cocoa/Cocoa.h>
+#include <stdlib.h>
 
 static int pasteboardChangeCount() {
     return (int)[[NSPasteboard generalPasteboard] changeCount];
 }
+
+// pasteboardRead returns a malloc'd copy of the general pasteboard's data
+// for the given UTI, or NULL if there is none.
+static void *pasteboardRead(const char *type, int *len) {
+    @autoreleasepool {
+        NSData *data = [[NSPasteboard generalPasteboard] dataForType:[NSString stringWithUTF8String:type]];
+        if (data == nil) return NULL;
+        *len = (int)data.length;
+        void *buf = malloc(data.length > 0 ? data.length : 1);
+        memcpy(buf, data.bytes, data.length);
+        return buf;
+    }
+}
+
+// pasteboardFileURLs returns the file URLs on the general pasteboard as a
+// malloc'd text/uri-list, or NULL if there are none.
+static char *pasteboardFileURLs() {
+    @autoreleasepool {
+        NSArray *urls = [[NSPasteboard generalPasteboard] readObjectsForClasses:@[[NSURL class]]
+                                                                        options:@{NSPasteboardURLReadingFileURLsOnlyKey: @YES}];
+        if (urls.count == 0) return NULL;
+        NSMutableArray *lines = [NSMutableArray arrayWithCapacity:urls.count];
+        for (NSURL *url in urls) [lines addObject:url.absoluteString];
+        return strdup([[lines componentsJoinedByString:@"\r\n"] UTF8String]);
+    }
+}
+
+// pasteboardWrite replaces the general pasteboard's contents with the file
+// URLs in uriList (may be NULL) and n (UTI, data) pairs, and returns the
+// change count of the write.
+static int pasteboardWrite(const char *uriList, int n, char **types, void **data, int *lens) {
+    @autoreleasepool {
+        NSPasteboard *pb = [NSPasteboard generalPasteboard];
+        NSInteger count = [pb clearContents];
+        if (uriList != NULL) {
+            NSMutableArray *urls = [NSMutableArray array];
+            for (NSString *line in [[NSString stringWithUTF8String:uriList] componentsSeparatedByCharactersInSet:[NSCharacterSet newlineCharacterSet]]) {
+                NSURL *url = line.length > 0 ? [NSURL URLWithString:line] : nil;
+                if (url != nil) [urls addObject:url];
+            }
+            if (urls.count > 0) [pb writeObjects:urls];
+        }
+        for (int i = 0; i < n; i++) {
+            [pb setData:[NSData dataWithBytes:data[i] length:lens[i]]
+                forType:[NSString stringWithUTF8String:types[i]]];
+        }
+        return (int)count;
+    }
+}
+
+// frontmostBundleID returns the bundle identifier of the frontmost
+// application as a malloc'd string, or NULL if it has none.
+static char *frontmostBundleID() {
+    @autoreleasepool {
+        NSString *id = [[NSWorkspace sharedWorkspace] frontmostApplication].bundleIdentifier;
+        return id == nil ? NULL : strdup(id.UTF8String);
+    }
+}
 */
 import "C"
 
-// getPasteboardChangeCount returns the current change count of the general pasteboard.
+import (
+	"os/exec"
+	"unsafe"
+
+	"golang.design/x/clipboard"
+)
+
+// pasteboardTypes maps formats to the UTIs they are written as. Files are
+// written as NSURL objects instead.
+var pasteboardTypes = map[ClipFormat]string{
+	FormatText:  "public.utf8-plain-text",
+	FormatImage: "public.png",
+	FormatHTML:  "public.html",
+	FormatRTF:   "public.rtf",
+
+	FormatConcealed: string(FormatConcealed),
+	FormatTransient: string(FormatTransient),
+}
+
+// darwinBackend talks to NSPasteboard, reading text and images via
+// golang.design/x/clipboard, and sends keystrokes through System Events.
+type darwinBackend struct{}
+
+// newSystemBackend initializes the macOS pasteboard backend.
+func newSystemBackend() (ClipboardBackend, error) {
+	if err := clipboard.Init(); err != nil {
+		return nil, err
+	}
+	return darwinBackend{}, nil
+}
+
+// ChangeCount returns the current change count of the general pasteboard.
 // This is used to detect when the clipboard content has changed.
-func getPasteboardChangeCount() int {
+func (darwinBackend) ChangeCount() int {
 	return int(C.pasteboardChangeCount())
 }
+
+func (darwinBackend) Read(format ClipFormat) []byte {
+	switch format {
+	case FormatText:
+		return clipboard.Read(clipboard.FmtText)
+	case FormatImage:
+		return clipboard.Read(clipboard.FmtImage)
+	case FormatFiles:
+		urls := C.pasteboardFileURLs()
+		if urls == nil {
+			return nil
+		}
+		defer C.free(unsafe.Pointer(urls))
+		return []byte(C.GoString(urls))
+	}
+	uti, ok := pasteboardTypes[format]
+	if !ok {
+		return nil
+	}
+	cType := C.CString(uti)
+	defer C.free(unsafe.Pointer(cType))
+	var n C.int
+	buf := C.pasteboardRead(cType, &n)
+	if buf == nil {
+		return nil
+	}
+	defer C.free(buf)
+	return C.GoBytes(buf, n)
+}
+
+// Write puts every representation on the pasteboard in one go, so pasting
+// apps can pick the richest one they understand. Clearing the pasteboard
+// bumps its change count once; adding the representations does not.
+func (darwinBackend) Write(data map[ClipFormat][]byte) (int, error) {
+	var uriList *C.char
+	if files, ok := data[FormatFiles]; ok {
+		uriList = C.CString(string(files))
+		defer C.free(unsafe.Pointer(uriList))
+	}
+
+	var types []*C.char
+	var bufs []unsafe.Pointer
+	var lens []C.int
+	for format, content := range data {
+		uti, ok := pasteboardTypes[format]
+		if !ok {
+			continue
+		}
+		types = append(types, C.CString(uti))
+		bufs = append(bufs, C.CBytes(content))
+		lens = append(lens, C.int(len(content)))
+	}
+	defer func() {
+		for i := range types {
+			C.free(unsafe.Pointer(types[i]))
+			C.free(bufs[i])
+		}
+	}()
+
+	// The slices hold only C pointers, so C may read them directly.
+	var typesPtr **C.char
+	var bufsPtr *unsafe.Pointer
+	var lensPtr *C.int
+	if len(types) > 0 {
+		typesPtr, bufsPtr, lensPtr = &types[0], &bufs[0], &lens[0]
+	}
+	return int(C.pasteboardWrite(uriList, C.int(len(types)), typesPtr, bufsPtr, lensPtr)), nil
+}
+
+// SourceApp returns the bundle ID of the frontmost application.
+func (darwinBackend) SourceApp() string {
+	id := C.frontmostBundleID()
+	if id == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(id))
+	return C.GoString(id)
+}
+
+// Paste simulates Cmd+V keystroke using AppleScript.
+func (darwinBackend) Paste() error {
+	script := `tell application "System Events" to keystroke "v" using command down`
+	return exec.Command("osascript", "-e", script).Run()
+}
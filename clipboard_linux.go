@@ -0,0 +1,589 @@
+package main
+
+/*
+#cgo LDFLAGS: -lX11 -lXfixes
+#include <limits.h>
+#include <pthread.h>
+#include <stdlib.h>
+#include <string.h>
+#include <unistd.h>
+#include <X11/Xlib.h>
+#include <X11/Xatom.h>
+#include <X11/extensions/Xfixes.h>
+
+// Xlib's default error handler exits the process, and X errors are routine
+// here: a requestor window destroyed mid-transfer, or a property too large
+// for the server. While a thread runs one of the functions below (between
+// beginXCall and endXCall), errors are recorded in lastXError instead;
+// errors on other threads, such as GTK's, go to the handler installed
+// before ours.
+static pthread_mutex_t errorHandlerLock = PTHREAD_MUTEX_INITIALIZER;
+static XErrorHandler previousErrorHandler;
+static __thread int ownXCalls;
+static __thread int lastXError;
+
+static int recordXError(Display *d, XErrorEvent *e) {
+    if (ownXCalls == 0 && previousErrorHandler) return previousErrorHandler(d, e);
+    lastXError = e->error_code;
+    return 0;
+}
+
+// beginXCall installs recordXError, again if another library replaced it.
+static void beginXCall(void) {
+    pthread_mutex_lock(&errorHandlerLock);
+    XErrorHandler prev = XSetErrorHandler(recordXError);
+    if (prev != recordXError) previousErrorHandler = prev;
+    pthread_mutex_unlock(&errorHandlerLock);
+    ownXCalls++;
+}
+
+static void endXCall(void) {
+    ownXCalls--;
+}
+
+// openSelectionWatch opens a dedicated display connection that receives an
+// XFixes event whenever the CLIPBOARD selection changes owner.
+static Display *openSelectionWatch(int *eventBase) {
+    beginXCall();
+    Display *d = XOpenDisplay(NULL);
+    int errorBase;
+    if (d && !XFixesQueryExtension(d, eventBase, &errorBase)) {
+        XCloseDisplay(d);
+        d = NULL;
+    }
+    if (d) {
+        Atom clip = XInternAtom(d, "CLIPBOARD", False);
+        XFixesSelectSelectionInput(d, DefaultRootWindow(d), clip, XFixesSetSelectionOwnerNotifyMask);
+    }
+    endXCall();
+    return d;
+}
+
+// waitSelectionChange blocks until the next CLIPBOARD owner change.
+static void waitSelectionChange(Display *d, int eventBase) {
+    beginXCall();
+    XEvent ev;
+    do {
+        XNextEvent(d, &ev);
+    } while (ev.type != eventBase + XFixesSelectionNotify);
+    endXCall();
+}
+
+// selectionOwner is the CLIPBOARD content we offer: data[i] as targets[i].
+typedef struct {
+    Display *d;
+    Window w;
+    Atom sel;
+    int n;
+    Atom *targets;
+    unsigned char **data;
+    unsigned long *sizes;
+} selectionOwner;
+
+static void freeSelectionOwner(selectionOwner *o) {
+    for (int i = 0; i < o->n; i++) free(o->data[i]);
+    free(o->data);
+    free(o->sizes);
+    free(o->targets);
+    XCloseDisplay(o->d);
+    free(o);
+}
+
+// maxPropertySize is the largest property one ChangeProperty request can
+// set on d. Larger ones would need an incremental (INCR) transfer.
+static unsigned long maxPropertySize(Display *d) {
+    long units = XExtendedMaxRequestSize(d);
+    if (units == 0) units = XMaxRequestSize(d);
+    return (unsigned long)units * 4 - 32; // Leaves room for the request header
+}
+
+// acquireSelection takes ownership of CLIPBOARD for the n targets named in
+// names, leaving out those larger than maxPropertySize and counting them in
+// *skipped. The owner takes over data, sizes and the buffers in data; they
+// are freed when serveSelection returns, or right away on failure.
+static selectionOwner *acquireSelection(int n, char **names, unsigned char **data, unsigned long *sizes, int *skipped) {
+    beginXCall();
+    selectionOwner *o = calloc(1, sizeof *o);
+    o->data = data;
+    o->sizes = sizes;
+    o->targets = calloc(n, sizeof(Atom));
+    o->d = XOpenDisplay(NULL);
+    if (!o->d) {
+        for (int i = 0; i < n; i++) free(data[i]);
+        free(data);
+        free(sizes);
+        free(o->targets);
+        free(o);
+        endXCall();
+        return NULL;
+    }
+    unsigned long limit = maxPropertySize(o->d);
+    *skipped = 0;
+    for (int i = 0; i < n; i++) {
+        if (sizes[i] > limit) {
+            free(data[i]);
+            (*skipped)++;
+            continue;
+        }
+        o->targets[o->n] = XInternAtom(o->d, names[i], False);
+        o->data[o->n] = data[i];
+        o->sizes[o->n] = sizes[i];
+        o->n++;
+    }
+    o->sel = XInternAtom(o->d, "CLIPBOARD", False);
+    o->w = XCreateSimpleWindow(o->d, DefaultRootWindow(o->d), 0, 0, 1, 1, 0, 0, 0);
+    if (o->n > 0) XSetSelectionOwner(o->d, o->sel, o->w, CurrentTime);
+    if (o->n == 0 || XGetSelectionOwner(o->d, o->sel) != o->w) {
+        freeSelectionOwner(o);
+        o = NULL;
+    }
+    endXCall();
+    return o;
+}
+
+// replyProperty sets property on the requestor window and reports whether
+// the server accepted it; the window may be gone already.
+static int replyProperty(Display *d, Window w, Atom property, Atom type, int format,
+        unsigned char *data, int n) {
+    lastXError = Success;
+    XChangeProperty(d, w, property, type, format, PropModeReplace, data, n);
+    XSync(d, False);
+    return lastXError == Success;
+}
+
+// serveSelection answers requests for the owned targets (and TARGETS)
+// until another client takes CLIPBOARD, then frees o. A request that
+// fails, say because its requestor disappeared, is refused.
+static void serveSelection(selectionOwner *o) {
+    beginXCall();
+    Atom targetsAtom = XInternAtom(o->d, "TARGETS", False);
+    XEvent ev;
+    for (;;) {
+        XNextEvent(o->d, &ev);
+        if (ev.type == SelectionClear && ev.xselectionclear.selection == o->sel) break;
+        if (ev.type != SelectionRequest) continue;
+
+        XSelectionRequestEvent *req = &ev.xselectionrequest;
+        XSelectionEvent reply = {0};
+        reply.type = SelectionNotify;
+        reply.display = req->display;
+        reply.requestor = req->requestor;
+        reply.selection = req->selection;
+        reply.target = req->target;
+        reply.time = req->time;
+        reply.property = req->property != None ? req->property : req->target; // Obsolete clients
+
+        int ok = 0;
+        if (req->target == targetsAtom) {
+            Atom *list = malloc((o->n + 1) * sizeof(Atom));
+            list[0] = targetsAtom;
+            memcpy(list + 1, o->targets, o->n * sizeof(Atom));
+            ok = replyProperty(o->d, req->requestor, reply.property, XA_ATOM, 32,
+                (unsigned char *)list, o->n + 1);
+            free(list);
+        } else {
+            int i = 0;
+            while (i < o->n && o->targets[i] != req->target) i++;
+            if (i < o->n) {
+                ok = replyProperty(o->d, req->requestor, reply.property, req->target, 8,
+                    o->data[i], (int)o->sizes[i]);
+            }
+        }
+        if (!ok) reply.property = None;
+        XSendEvent(o->d, req->requestor, False, NoEventMask, (XEvent *)&reply);
+        XSync(o->d, False);
+    }
+    freeSelectionOwner(o);
+    endXCall();
+}
+
+// selectionReader is the connection and window the clipboard is read
+// through, kept open for the life of the process.
+typedef struct {
+    Display *d;
+    Window w;
+    Atom sel;
+    Atom prop;
+    Atom incr;
+} selectionReader;
+
+static selectionReader *openSelectionReader(void) {
+    beginXCall();
+    Display *d = XOpenDisplay(NULL);
+    if (!d) {
+        endXCall();
+        return NULL;
+    }
+    selectionReader *r = calloc(1, sizeof *r);
+    r->d = d;
+    r->w = XCreateSimpleWindow(d, DefaultRootWindow(d), 0, 0, 1, 1, 0, 0, 0);
+    r->sel = XInternAtom(d, "CLIPBOARD", False);
+    r->prop = XInternAtom(d, "CLIPBOARD_ISLAND", False);
+    r->incr = XInternAtom(d, "INCR", False);
+    endXCall();
+    return r;
+}
+
+// convertSelection asks the CLIPBOARD owner for target, waiting up to
+// timeoutMs, and returns the property it answered with in *data (to be
+// released with XFree), or 0 if the target is not offered. Replies to
+// earlier requests that timed out are discarded first. Incremental (INCR)
+// transfers are not supported. Must be called between beginXCall and
+// endXCall.
+static int convertSelection(selectionReader *r, Atom target, Atom *type, int *format,
+        unsigned long *items, unsigned char **data, int timeoutMs) {
+    XEvent ev;
+    XSync(r->d, False);
+    while (XPending(r->d)) XNextEvent(r->d, &ev);
+    XDeleteProperty(r->d, r->w, r->prop);
+    XConvertSelection(r->d, r->sel, target, r->prop, r->w, CurrentTime);
+    XFlush(r->d);
+
+    for (int waited = 0; waited < timeoutMs;) {
+        if (!XPending(r->d)) {
+            usleep(5000);
+            waited += 5;
+            continue;
+        }
+        XNextEvent(r->d, &ev);
+        if (ev.type != SelectionNotify || ev.xselection.target != target) continue;
+        if (ev.xselection.property == None) return 0;
+
+        unsigned long after;
+        *data = NULL;
+        if (XGetWindowProperty(r->d, r->w, r->prop, 0, LONG_MAX / 4, True, AnyPropertyType,
+                type, format, items, &after, data) != Success || *data == NULL) {
+            return 0;
+        }
+        if (*type == r->incr) {
+            XFree(*data);
+            return 0;
+        }
+        return 1;
+    }
+    return 0;
+}
+
+// readSelection reads the CLIPBOARD contents as target through r. It
+// returns the size and a malloc'd copy of the data in *out, or -1 if the
+// target is not offered.
+static long readSelection(selectionReader *r, const char *target, unsigned char **out, int timeoutMs) {
+    beginXCall();
+    Atom type;
+    int format;
+    unsigned long items;
+    unsigned char *data;
+    long n = -1;
+    if (convertSelection(r, XInternAtom(r->d, target, False), &type, &format, &items, &data, timeoutMs)) {
+        if (format == 8) {
+            n = (long)items;
+            *out = malloc(items > 0 ? items : 1);
+            memcpy(*out, data, items);
+        }
+        XFree(data);
+    }
+    endXCall();
+    return n;
+}
+
+// readTargets asks the CLIPBOARD owner which targets it offers through r.
+// It returns their names, one per line, as a malloc'd string, or NULL if
+// the owner did not answer.
+static char *readTargets(selectionReader *r, int timeoutMs) {
+    beginXCall();
+    Atom type;
+    int format;
+    unsigned long items;
+    unsigned char *data;
+    char *list = NULL;
+    Atom targets = XInternAtom(r->d, "TARGETS", False);
+    if (convertSelection(r, targets, &type, &format, &items, &data, timeoutMs)) {
+        if (format == 32) {
+            // Format 32 properties come back as longs
+            Atom *atoms = malloc((items > 0 ? items : 1) * sizeof(Atom));
+            for (unsigned long i = 0; i < items; i++) atoms[i] = ((unsigned long *)data)[i];
+            char **names = calloc(items > 0 ? items : 1, sizeof(char *));
+            XGetAtomNames(r->d, atoms, (int)items, names);
+            size_t size = 1;
+            for (unsigned long i = 0; i < items; i++) {
+                if (names[i]) size += strlen(names[i]) + 1;
+            }
+            list = calloc(1, size);
+            for (unsigned long i = 0; i < items; i++) {
+                if (!names[i]) continue;
+                strcat(list, names[i]);
+                strcat(list, "\n");
+                XFree(names[i]);
+            }
+            free(names);
+            free(atoms);
+        }
+        XFree(data);
+    }
+    endXCall();
+    return list;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"golang.design/x/clipboard"
+)
+
+// newSystemBackend picks the Wayland backend when running under a Wayland
+// compositor with wl-clipboard installed, and the X11 backend otherwise.
+func newSystemBackend() (ClipboardBackend, error) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if _, err := exec.LookPath("wl-paste"); err == nil {
+			return newWaylandBackend()
+		}
+	}
+	return newX11Backend()
+}
+
+// x11Backend reads text and images via golang.design/x/clipboard and other
+// formats directly from the CLIPBOARD selection, serves every written
+// representation from its own selection owner, counts owner changes with
+// XFixes, and injects Ctrl+V through xdotool.
+type x11Backend struct {
+	*changeCounter
+	reader *C.selectionReader
+
+	// readMu serializes reads through reader and guards the targets the
+	// current owner offers, fetched once per change; see offers.
+	readMu     sync.Mutex
+	offered    map[string]bool // nil if the owner did not answer TARGETS
+	offeredFor int             // Change count offered was fetched at; -1 before the first fetch
+}
+
+// newX11Backend connects to $DISPLAY and starts listening for selection changes.
+func newX11Backend() (*x11Backend, error) {
+	if err := clipboard.Init(); err != nil {
+		return nil, err
+	}
+	var eventBase C.int
+	display := C.openSelectionWatch(&eventBase)
+	if display == nil {
+		return nil, errors.New("X11 display or XFixes extension unavailable")
+	}
+	reader := C.openSelectionReader()
+	if reader == nil {
+		return nil, errors.New("X11 display unavailable")
+	}
+	b := &x11Backend{changeCounter: newChangeCounter(), reader: reader, offeredFor: -1}
+	go b.watchSelection(display, eventBase)
+	return b, nil
+}
+
+// watchSelection bumps the change count on every selection owner change.
+// It owns the display connection and runs for the life of the process.
+func (b *x11Backend) watchSelection(display *C.Display, eventBase C.int) {
+	for {
+		C.waitSelectionChange(display, eventBase)
+		b.bump()
+	}
+}
+
+// writeEchoTimeout bounds how long Write waits to see the change its own
+// write causes; the watchers report it asynchronously.
+const writeEchoTimeout = 500 * time.Millisecond
+
+// changeCounter counts the clipboard changes a watcher goroutine reports,
+// signals them on Changes, and lets Write find the count it produced.
+type changeCounter struct {
+	count   atomic.Int64
+	changes chan struct{}
+}
+
+func newChangeCounter() *changeCounter {
+	return &changeCounter{changes: make(chan struct{}, 1)}
+}
+
+// bump records one change and wakes the watcher, coalescing with any
+// wake-up it has not consumed yet.
+func (c *changeCounter) bump() {
+	c.count.Add(1)
+	select {
+	case c.changes <- struct{}{}:
+	default:
+	}
+}
+
+func (c *changeCounter) ChangeCount() int {
+	return int(c.count.Load())
+}
+
+func (c *changeCounter) Changes() <-chan struct{} {
+	return c.changes
+}
+
+// awaitChange returns the first change count after before, or the current
+// count if no change is reported within writeEchoTimeout.
+func (c *changeCounter) awaitChange(before int) int {
+	deadline := time.Now().Add(writeEchoTimeout)
+	for time.Now().Before(deadline) {
+		if count := c.ChangeCount(); count != before {
+			return count
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	log.Println("[clipboard] own clipboard write was not reported in time")
+	return c.ChangeCount()
+}
+
+// x11Targets lists the selection targets each format is offered as, most
+// specific first.
+var x11Targets = map[ClipFormat][]string{
+	FormatText:  {"UTF8_STRING", "text/plain;charset=utf-8", "text/plain"},
+	FormatImage: {"image/png"},
+	FormatHTML:  {"text/html"},
+	FormatRTF:   {"text/rtf", "application/rtf"},
+	FormatFiles: {"text/uri-list"},
+
+	// KeePassXC and KDE apps flag passwords with x-kde-passwordManagerHint.
+	FormatConcealed: {"x-kde-passwordManagerHint", string(FormatConcealed)},
+	FormatTransient: {string(FormatTransient)},
+}
+
+// gnomeCopiedFiles is the file list target GNOME file managers use.
+const gnomeCopiedFiles = "x-special/gnome-copied-files"
+
+// selectionTimeout bounds how long a read waits for the selection owner.
+const selectionTimeout = 1000 // ms
+
+// Read asks the owner only for targets it lists in TARGETS, so a capture
+// costs one round trip per format present rather than one per format
+// probed. Text always goes through golang.design/x/clipboard, and so do
+// images unless the owner answered TARGETS without image/png.
+func (b *x11Backend) Read(format ClipFormat) []byte {
+	switch format {
+	case FormatText:
+		return clipboard.Read(clipboard.FmtText)
+	case FormatImage:
+		if offered, known := b.offers(); known && !offered["image/png"] {
+			return nil
+		}
+		return clipboard.Read(clipboard.FmtImage)
+	}
+	offered, _ := b.offers()
+	for _, target := range x11Targets[format] {
+		if offered[target] {
+			return b.readSelection(target)
+		}
+	}
+	if format == FormatFiles && offered[gnomeCopiedFiles] {
+		// "copy" or "cut", then one URL per line
+		if data := b.readSelection(gnomeCopiedFiles); data != nil {
+			if _, urls, ok := strings.Cut(string(data), "\n"); ok {
+				return []byte(urls)
+			}
+		}
+	}
+	return nil
+}
+
+// offers returns the targets the current CLIPBOARD owner offers, asking it
+// at most once per change. known is false if the owner did not answer.
+func (b *x11Backend) offers() (offered map[string]bool, known bool) {
+	count := b.ChangeCount()
+	b.readMu.Lock()
+	defer b.readMu.Unlock()
+	if b.offeredFor != count {
+		b.offered, b.offeredFor = nil, count
+		if list := C.readTargets(b.reader, selectionTimeout); list != nil {
+			b.offered = make(map[string]bool)
+			for _, target := range strings.Fields(C.GoString(list)) {
+				b.offered[target] = true
+			}
+			C.free(unsafe.Pointer(list))
+		}
+	}
+	return b.offered, b.offered != nil
+}
+
+// readSelection returns the CLIPBOARD contents as target, or nil.
+func (b *x11Backend) readSelection(target string) []byte {
+	name := C.CString(target)
+	defer C.free(unsafe.Pointer(name))
+	b.readMu.Lock()
+	defer b.readMu.Unlock()
+	var out *C.uchar
+	n := C.readSelection(b.reader, name, &out, selectionTimeout)
+	if n < 0 {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(out))
+	return C.GoBytes(unsafe.Pointer(out), C.int(n))
+}
+
+// Write takes ownership of CLIPBOARD with every representation in data and
+// serves them until another client copies something.
+func (b *x11Backend) Write(data map[ClipFormat][]byte) (int, error) {
+	before := b.ChangeCount()
+	var names []string
+	var contents [][]byte
+	for format, content := range data {
+		for _, target := range x11Targets[format] {
+			names = append(names, target)
+			contents = append(contents, content)
+		}
+		if format == FormatFiles {
+			names = append(names, gnomeCopiedFiles)
+			contents = append(contents, append([]byte("copy\n"), content...))
+		}
+	}
+	if len(names) == 0 {
+		return before, errors.New("nothing to offer on the X11 clipboard")
+	}
+
+	n := len(names)
+	cNames := (*[1 << 20]*C.char)(C.malloc(C.size_t(n) * C.size_t(unsafe.Sizeof(uintptr(0)))))[:n:n]
+	cData := (*[1 << 20]*C.uchar)(C.malloc(C.size_t(n) * C.size_t(unsafe.Sizeof(uintptr(0)))))[:n:n]
+	cSizes := (*[1 << 20]C.ulong)(C.malloc(C.size_t(n) * C.size_t(unsafe.Sizeof(C.ulong(0)))))[:n:n]
+	for i := range names {
+		cNames[i] = C.CString(names[i])
+		cData[i] = (*C.uchar)(C.CBytes(contents[i]))
+		cSizes[i] = C.ulong(len(contents[i]))
+	}
+	var skipped C.int
+	owner := C.acquireSelection(C.int(n), &cNames[0], &cData[0], &cSizes[0], &skipped) // Takes cData and cSizes
+	for i := range cNames {
+		C.free(unsafe.Pointer(cNames[i]))
+	}
+	C.free(unsafe.Pointer(&cNames[0]))
+	if owner == nil {
+		if int(skipped) == n {
+			return before, errors.New("clipboard content too large for an X11 transfer")
+		}
+		return before, errors.New("failed to take ownership of the X11 clipboard")
+	}
+	if skipped > 0 {
+		log.Printf("[clipboard] not offering %d X11 targets too large for one transfer", skipped)
+	}
+	go C.serveSelection(owner)
+	return b.awaitChange(before), nil
+}
+
+// SourceApp returns the WM_CLASS of the active window, via xdotool.
+func (b *x11Backend) SourceApp() string {
+	out, err := exec.Command("xdotool", "getactivewindow", "getwindowclassname").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Paste simulates Ctrl+V in the focused window using xdotool.
+func (b *x11Backend) Paste() error {
+	return exec.Command("xdotool", "key", "--clearmodifiers", "ctrl+v").Run()
+}
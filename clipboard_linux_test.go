@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// These tests need a real display server. Under CI, run them with Xvfb:
+//
+//	Xvfb :99 & DISPLAY=:99 go test ./...
+
+// checkOwnWrite verifies Write reported the change count its write produced
+// and the backend signalled the change.
+func checkOwnWrite(t *testing.T, b interface {
+	ClipboardBackend
+	ChangeNotifier
+}, before, written int) {
+	t.Helper()
+	if written == before || written != b.ChangeCount() {
+		t.Errorf("Write returned %d; count was %d before, %d now", written, before, b.ChangeCount())
+	}
+	select {
+	case <-b.Changes():
+	case <-time.After(2 * time.Second):
+		t.Error("no change notification")
+	}
+}
+
+// TestX11Backend_RoundTrip verifies XFixes change notification and text read/write.
+func TestX11Backend_RoundTrip(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" {
+		t.Skip("DISPLAY not set; run under Xvfb")
+	}
+	b, err := newX11Backend()
+	if err != nil {
+		t.Fatalf("newX11Backend: %v", err)
+	}
+
+	before := b.ChangeCount()
+	written, err := b.Write(map[ClipFormat][]byte{FormatText: []byte("x11 round trip")})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	checkOwnWrite(t, b, before, written)
+
+	if got := string(b.Read(FormatText)); got != "x11 round trip" {
+		t.Errorf("expected 'x11 round trip', got %q", got)
+	}
+}
+
+// TestX11Backend_SkipsOversizedTargets verifies a representation too large
+// for one ChangeProperty request is not offered, and the rest still are.
+func TestX11Backend_SkipsOversizedTargets(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" {
+		t.Skip("DISPLAY not set; run under Xvfb")
+	}
+	b, err := newX11Backend()
+	if err != nil {
+		t.Fatalf("newX11Backend: %v", err)
+	}
+
+	if _, err := b.Write(map[ClipFormat][]byte{
+		FormatText:  []byte("next to a huge image"),
+		FormatImage: make([]byte, 64<<20),
+	}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := string(b.Read(FormatText)); got != "next to a huge image" {
+		t.Errorf("expected the text served, got %q", got)
+	}
+	if data := b.readSelection("image/png"); data != nil {
+		t.Errorf("expected the image not offered, got %d bytes", len(data))
+	}
+}
+
+// TestWaylandBackend_RoundTrip verifies wl-paste --watch change notification.
+func TestWaylandBackend_RoundTrip(t *testing.T) {
+	if os.Getenv("WAYLAND_DISPLAY") == "" {
+		t.Skip("WAYLAND_DISPLAY not set")
+	}
+	if _, err := exec.LookPath("wl-copy"); err != nil {
+		t.Skip("wl-clipboard not installed")
+	}
+	b, err := newWaylandBackend()
+	if err != nil {
+		t.Fatalf("newWaylandBackend: %v", err)
+	}
+
+	before := b.ChangeCount()
+	written, err := b.Write(map[ClipFormat][]byte{FormatText: []byte("wayland round trip")})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	checkOwnWrite(t, b, before, written)
+
+	if got := string(b.Read(FormatText)); got != "wayland round trip" {
+		t.Errorf("expected 'wayland round trip', got %q", got)
+	}
+}
+
+// TestCursorAndScreen_InBounds verifies the pointer lies on the reported screen.
+func TestCursorAndScreen_InBounds(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" {
+		t.Skip("DISPLAY not set; run under Xvfb")
+	}
+	cx, cy, sw, sh, scale := cursorAndScreen()
+	if scale != 1 {
+		t.Errorf("expected scale 1 on X11, got %d", scale)
+	}
+	if cx < 0 || cy < 0 || cx > sw || cy > sh {
+		t.Errorf("cursor (%d,%d) outside screen %dx%d", cx, cy, sw, sh)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -148,109 +149,30 @@ func TestAddItem_DedupPreservesPinnedStatus(t *testing.T) {
 	}
 }
 
-// BenchmarkAddItem benchmarks adding items to the history.
-func BenchmarkAddItem(b *testing.B) {
+// TestClearHistory_KeepsPinned verifies clearing removes only unpinned items.
+func TestClearHistory_KeepsPinned(t *testing.T) {
 	app := &App{}
-	for i := 0; i < b.N; i++ {
-		app.addItem(strings.Repeat("x", i%100))
-	}
-}
-
-// TestAddItem_SkipsLastWritten verifies that addItem skips text that matches lastWritten.
-// This prevents re-capturing text that was just pasted from the clipboard manager.
-func TestAddItem_SkipsLastWritten(t *testing.T) {
-	app := &App{}
-	app.addItem("first")
-	app.addItem("second")
-
-	// Simulate that we just wrote "second" via SelectItem
-	app.lastWritten = "second"
+	app.addItem("drop")
+	app.addItem("keep")
+	app.addItem("drop too")
+	app.TogglePin(app.history[1].ID)
 
-	// Adding "second" again should be skipped
-	app.addItem("second")
-
-	if len(app.history) != 2 {
-		t.Fatalf("expected 2 items (skipped lastWritten), got %d", len(app.history))
+	if removed := app.ClearHistory(); removed != 2 {
+		t.Errorf("expected 2 removed, got %d", removed)
 	}
-	if app.history[0].Text != "second" {
-		t.Errorf("expected first item to still be 'second', got '%s'", app.history[0].Text)
-	}
-}
-
-// TestAddItem_LastWrittenCleared verifies that lastWritten is cleared after one skip.
-// This ensures future copies of the same text are captured again.
-func TestAddItem_LastWrittenCleared(t *testing.T) {
-	app := &App{}
-	app.addItem("item")
-	app.addItem("other")
-
-	// Simulate that we just wrote "item" via SelectItem
-	app.lastWritten = "item"
-
-	// First add should be skipped and clear lastWritten
-	app.addItem("item")
-	if len(app.history) != 2 {
-		t.Fatalf("expected 2 items after skip, got %d", len(app.history))
-	}
-	// "item" should NOT have moved to front (it was skipped)
-	if app.history[0].Text != "other" {
-		t.Errorf("expected first item to still be 'other' (skip didn't move), got '%s'", app.history[0].Text)
-	}
-
-	// Second add should succeed (lastWritten was cleared)
-	// "item" should be deduped (removed from position 1, added to front)
-	app.addItem("item")
-	if len(app.history) != 2 {
-		t.Fatalf("expected 2 items after re-add, got %d", len(app.history))
-	}
-	// "item" should now be at front (normal dedup behavior)
-	if app.history[0].Text != "item" {
-		t.Errorf("expected first item to be 'item' (moved to front), got '%s'", app.history[0].Text)
-	}
-}
-
-// TestAddItem_LastWrittenDoesNotAffectOtherItems verifies that lastWritten only skips exact matches.
-func TestAddItem_LastWrittenDoesNotAffectOtherItems(t *testing.T) {
-	app := &App{}
-	app.addItem("first")
-
-	// Simulate that we just wrote "different" via SelectItem
-	app.lastWritten = "different"
-
-	// Adding "first" should work normally
-	app.addItem("first")
-
-	if len(app.history) != 1 {
-		t.Fatalf("expected 1 item (dedup), got %d", len(app.history))
+	if len(app.history) != 1 || app.history[0].Text != "keep" {
+		t.Fatalf("expected only 'keep', got %+v", app.history)
 	}
-	// Should be moved to front
-	if app.history[0].Text != "first" {
-		t.Errorf("expected 'first', got '%s'", app.history[0].Text)
+	if removed := app.ClearHistory(); removed != 0 {
+		t.Errorf("expected nothing left to remove, got %d", removed)
 	}
 }
 
-// TestSelectItem_UpdatesLastWritten verifies that SelectItem sets lastWritten to prevent re-capture.
-func TestSelectItem_UpdatesLastWritten(t *testing.T) {
+// BenchmarkAddItem benchmarks adding items to the history.
+func BenchmarkAddItem(b *testing.B) {
 	app := &App{}
-	app.addItem("item1")
-	app.addItem("item2")
-	app.addItem("item3")
-
-	// SelectItem would normally write to clipboard and hide window,
-	// but we can't test that without a window. We verify the state changes instead.
-	// The lastWritten should be set to the selected item's text
-	app.mu.Lock()
-	app.lastWritten = app.history[1].Text // Simulate what SelectItem does
-	app.mu.Unlock()
-
-	if app.lastWritten != "item2" {
-		t.Errorf("expected lastWritten to be 'item2', got '%s'", app.lastWritten)
-	}
-
-	// Subsequent add of same text should be skipped
-	app.addItem("item2")
-	if len(app.history) != 3 {
-		t.Errorf("expected 3 items (skipped), got %d", len(app.history))
+	for i := 0; i < b.N; i++ {
+		app.addItem(strings.Repeat("x", i%100))
 	}
 }
 
@@ -284,28 +206,28 @@ func TestTogglePin(t *testing.T) {
 	}
 
 	// Toggle pin on
-	app.TogglePin(1)
+	app.TogglePin(app.history[1].ID)
 	if !app.history[1].Pinned {
 		t.Error("item should be pinned after toggle")
 	}
 
 	// Toggle pin off
-	app.TogglePin(1)
+	app.TogglePin(app.history[1].ID)
 	if app.history[1].Pinned {
 		t.Error("item should not be pinned after second toggle")
 	}
 }
 
-// TestTogglePin_OutOfBounds ensures TogglePin handles invalid indices gracefully.
-func TestTogglePin_OutOfBounds(t *testing.T) {
+// TestTogglePin_UnknownID ensures TogglePin handles unknown IDs gracefully.
+func TestTogglePin_UnknownID(t *testing.T) {
 	app := &App{}
 	app.addItem("item")
 
-	// Should not panic on negative index
-	app.TogglePin(-1)
+	// Should not panic on empty ID
+	app.TogglePin("")
 
-	// Should not panic on out of bounds index
-	app.TogglePin(10)
+	// Should not panic on unknown ID
+	app.TogglePin("does-not-exist")
 
 	// Item should still be there
 	if len(app.history) != 1 {
@@ -321,7 +243,7 @@ func TestDeleteItem(t *testing.T) {
 	app.addItem("item3")
 
 	// Delete middle item
-	app.DeleteItem(1)
+	app.DeleteItem(app.history[1].ID)
 
 	if len(app.history) != 2 {
 		t.Fatalf("expected 2 items after delete, got %d", len(app.history))
@@ -334,21 +256,21 @@ func TestDeleteItem(t *testing.T) {
 	}
 }
 
-// TestDeleteItem_OutOfBounds ensures DeleteItem handles invalid indices gracefully.
-func TestDeleteItem_OutOfBounds(t *testing.T) {
+// TestDeleteItem_UnknownID ensures DeleteItem handles unknown IDs gracefully.
+func TestDeleteItem_UnknownID(t *testing.T) {
 	app := &App{}
 	app.addItem("item1")
 	app.addItem("item2")
 
-	// Should not panic on negative index
-	app.DeleteItem(-1)
+	// Should not panic on empty ID
+	app.DeleteItem("")
 
 	if len(app.history) != 2 {
 		t.Errorf("expected 2 items, got %d", len(app.history))
 	}
 
-	// Should not panic on out of bounds index
-	app.DeleteItem(10)
+	// Should not panic on unknown ID
+	app.DeleteItem("does-not-exist")
 
 	if len(app.history) != 2 {
 		t.Errorf("expected 2 items, got %d", len(app.history))
@@ -645,7 +567,7 @@ func TestDeleteItem_First(t *testing.T) {
 	app.addItem("second")
 	app.addItem("third")
 
-	app.DeleteItem(0) // Delete first ("third")
+	app.DeleteItem(app.history[0].ID) // Delete first ("third")
 
 	if len(app.history) != 2 {
 		t.Fatalf("expected 2 items, got %d", len(app.history))
@@ -662,7 +584,7 @@ func TestDeleteItem_Last(t *testing.T) {
 	app.addItem("second")
 	app.addItem("third")
 
-	app.DeleteItem(2) // Delete last ("first")
+	app.DeleteItem(app.history[2].ID) // Delete last ("first")
 
 	if len(app.history) != 2 {
 		t.Fatalf("expected 2 items, got %d", len(app.history))
@@ -672,6 +594,58 @@ func TestDeleteItem_Last(t *testing.T) {
 	}
 }
 
+// TestAddItem_AssignsUniqueIDs verifies every new item gets a distinct ID and timestamps.
+func TestAddItem_AssignsUniqueIDs(t *testing.T) {
+	app := &App{}
+	app.addItem("first")
+	app.addItem("second")
+
+	if app.history[0].ID == "" || app.history[1].ID == "" {
+		t.Fatal("expected items to have IDs")
+	}
+	if app.history[0].ID == app.history[1].ID {
+		t.Errorf("expected distinct IDs, both are %q", app.history[0].ID)
+	}
+	if app.history[0].CreatedAt.IsZero() || app.history[0].LastUsedAt.IsZero() {
+		t.Error("expected CreatedAt and LastUsedAt to be set")
+	}
+}
+
+// TestAddItem_DedupKeepsID verifies a re-copied item keeps its ID when moved to the front.
+func TestAddItem_DedupKeepsID(t *testing.T) {
+	app := &App{}
+	app.addItem("first")
+	id := app.history[0].ID
+	created := app.history[0].CreatedAt
+	app.addItem("second")
+	app.addItem("first")
+
+	if app.history[0].ID != id {
+		t.Errorf("expected ID %q to survive dedup, got %q", id, app.history[0].ID)
+	}
+	if !app.history[0].CreatedAt.Equal(created) {
+		t.Error("expected CreatedAt to survive dedup")
+	}
+}
+
+// TestTogglePin_StableAcrossCapture verifies that pinning by ID hits the intended item
+// even when a new clip is captured between reading history and the click.
+func TestTogglePin_StableAcrossCapture(t *testing.T) {
+	app := &App{}
+	app.addItem("target")
+	app.addItem("other")
+	target := app.GetHistory()[1].ID
+
+	app.addItem("captured meanwhile") // Shifts every index by one
+
+	app.TogglePin(target)
+	for _, item := range app.history {
+		if item.Pinned != (item.Text == "target") {
+			t.Errorf("item %q pinned=%v", item.Text, item.Pinned)
+		}
+	}
+}
+
 // TestAddItem_TypeField verifies Type field is set correctly.
 func TestAddItem_TypeField(t *testing.T) {
 	app := &App{}
@@ -713,3 +687,141 @@ func BenchmarkAddImageItem(b *testing.B) {
 		app.addImageItem(fakeImage)
 	}
 }
+
+// TestHashBytes_SameSizeSameHeader verifies images with equal headers and sizes don't collide.
+func TestHashBytes_SameSizeSameHeader(t *testing.T) {
+	a := make([]byte, 4096)
+	b := make([]byte, 4096)
+	copy(a, []byte{0x89, 0x50, 0x4E, 0x47})
+	copy(b, []byte{0x89, 0x50, 0x4E, 0x47})
+	b[4000] = 1 // Differ only past the header
+
+	if hashBytes(a) == hashBytes(b) {
+		t.Error("different images with the same header and size must not collide")
+	}
+}
+
+// TestAddImageItem_SameSizeNotDeduped verifies distinct same-size screenshots are both kept.
+func TestAddImageItem_SameSizeNotDeduped(t *testing.T) {
+	app := &App{}
+	img1 := make([]byte, 2048)
+	img2 := make([]byte, 2048)
+	copy(img1, []byte{0x89, 0x50, 0x4E, 0x47})
+	copy(img2, []byte{0x89, 0x50, 0x4E, 0x47})
+	img2[1500] = 7
+
+	app.addImageItem(img1)
+	app.addImageItem(img2)
+
+	if len(app.history) != 2 {
+		t.Fatalf("expected 2 image items, got %d", len(app.history))
+	}
+}
+
+// TestAddImageItem_EvictionForgetsHash verifies an evicted image can be captured again.
+func TestAddImageItem_EvictionForgetsHash(t *testing.T) {
+	app := &App{}
+	img := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0x09}
+	app.addImageItem(img)
+	for i := 0; i < 30; i++ {
+		app.addItem(string(rune('a' + i)))
+	}
+	if len(app.imageIndex) != 0 {
+		t.Fatalf("expected evicted image to leave the index, got %v", app.imageIndex)
+	}
+
+	app.addImageItem(img)
+	if app.history[0].Type != TypeImage {
+		t.Error("expected re-captured image at the front")
+	}
+}
+
+// TestAddItem_ConfigurableMaxItems verifies a raised item limit keeps thousands of clips.
+func TestAddItem_ConfigurableMaxItems(t *testing.T) {
+	app := &App{}
+	app.settings.MaxItems = 5000
+	for i := 0; i < 2000; i++ {
+		app.addItem(fmt.Sprintf("clip %d", i))
+	}
+
+	if len(app.history) != 2000 {
+		t.Fatalf("expected 2000 items, got %d", len(app.history))
+	}
+}
+
+// TestAddImageItem_MaxImages verifies old images are evicted without touching text clips.
+func TestAddImageItem_MaxImages(t *testing.T) {
+	app := &App{}
+	app.settings.MaxImages = 2
+	app.addItem("text before")
+	for i := byte(0); i < 4; i++ {
+		app.addImageItem([]byte{0x89, 0x50, 0x4E, 0x47, i})
+	}
+	app.addItem("text after")
+
+	images, texts := 0, 0
+	for _, item := range app.history {
+		if item.Type == TypeImage {
+			images++
+		} else {
+			texts++
+		}
+	}
+	if images != 2 || texts != 2 {
+		t.Errorf("expected 2 images and 2 texts, got %d and %d", images, texts)
+	}
+	if len(app.imageIndex) != 2 {
+		t.Errorf("expected imageIndex to track 2 images, got %d", len(app.imageIndex))
+	}
+}
+
+// TestTrimToCap_MaxTotalBytes verifies an oversized item is evicted but smaller older ones stay.
+func TestTrimToCap_MaxTotalBytes(t *testing.T) {
+	app := &App{}
+	app.settings.MaxTotalBytes = 100
+	app.history = []ClipItem{
+		{ID: "1", Type: TypeText, Text: strings.Repeat("a", 60)},
+		{ID: "2", Type: TypeText, Text: strings.Repeat("b", 60)}, // Would exceed 100
+		{ID: "3", Type: TypeText, Text: strings.Repeat("c", 30)},
+	}
+
+	kept := app.trimToCap()
+
+	if len(kept) != 2 || kept[0].ID != "1" || kept[1].ID != "3" {
+		t.Errorf("expected items 1 and 3 kept, got %+v", kept)
+	}
+}
+
+// TestTrimToCap_KeepsOrder verifies pinned items keep their position instead of moving to the end.
+func TestTrimToCap_KeepsOrder(t *testing.T) {
+	app := &App{}
+	app.settings.MaxItems = 3
+	app.history = []ClipItem{
+		{ID: "a", Text: "a"},
+		{ID: "b", Text: "b", Pinned: true},
+		{ID: "c", Text: "c"},
+		{ID: "d", Text: "d"},
+	}
+
+	kept := app.trimToCap()
+
+	var ids []string
+	for _, item := range kept {
+		ids = append(ids, item.ID)
+	}
+	if strings.Join(ids, ",") != "a,b,c" {
+		t.Errorf("expected a,b,c, got %v", ids)
+	}
+}
+
+// TestAddItem_MaxTextLength verifies over-long text is not captured.
+func TestAddItem_MaxTextLength(t *testing.T) {
+	app := &App{}
+	app.settings.MaxTextLength = 10
+	app.addItem("short")
+	app.addItem(strings.Repeat("x", 11))
+
+	if len(app.history) != 1 || app.history[0].Text != "short" {
+		t.Errorf("expected only 'short', got %+v", app.history)
+	}
+}
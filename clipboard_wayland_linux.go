@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+)
+
+// waylandBackend shells out to wl-clipboard (wl-paste/wl-copy) and injects
+// Ctrl+V through ydotool, since Wayland offers no portable in-process API.
+type waylandBackend struct {
+	*changeCounter
+}
+
+// newWaylandBackend starts `wl-paste --watch`, which prints a line on every
+// clipboard change, and counts those lines.
+func newWaylandBackend() (*waylandBackend, error) {
+	cmd := exec.Command("wl-paste", "--watch", "echo")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	b := &waylandBackend{newChangeCounter()}
+	go func() {
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			b.bump()
+		}
+		if err := cmd.Wait(); err != nil {
+			log.Printf("[clipboard] wl-paste --watch exited: %v", err)
+		}
+	}()
+	return b, nil
+}
+
+// waylandTextTypes are the MIME types tried for FormatText, plain text
+// first. The bare "text" lets wl-paste pick any text/* offer, which may be
+// text/html, so it is only the last resort.
+var waylandTextTypes = []string{"text/plain;charset=utf-8", "text/plain", "text"}
+
+func (b *waylandBackend) Read(format ClipFormat) []byte {
+	switch format {
+	case FormatText:
+		for _, mime := range waylandTextTypes {
+			if data := wlPaste(mime); data != nil {
+				return data
+			}
+		}
+		return nil
+	case FormatConcealed, FormatTransient:
+		for _, mime := range x11Targets[format] { // Wayland apps offer the X11 names
+			if data := wlPaste(mime); data != nil {
+				return data
+			}
+		}
+		return nil
+	}
+	return wlPaste(string(format))
+}
+
+// wlPaste returns the clipboard content offered as mime, or nil.
+func wlPaste(mime string) []byte {
+	out, err := exec.Command("wl-paste", "--no-newline", "--type", mime).Output()
+	if err != nil {
+		return nil // Nothing offered in this format
+	}
+	if out == nil {
+		out = []byte{} // Offered but empty, as markers often are
+	}
+	return out
+}
+
+// SourceApp returns "": Wayland does not tell clients which app is focused.
+func (b *waylandBackend) SourceApp() string {
+	return ""
+}
+
+// Write offers only the image or text: wl-copy serves a single type.
+func (b *waylandBackend) Write(data map[ClipFormat][]byte) (int, error) {
+	before := b.ChangeCount()
+	format := FormatText
+	if _, ok := data[FormatImage]; ok {
+		format = FormatImage
+	}
+	cmd := exec.Command("wl-copy", "--type", string(format))
+	cmd.Stdin = bytes.NewReader(data[format])
+	if err := cmd.Run(); err != nil {
+		return before, fmt.Errorf("wl-copy: %w", err)
+	}
+	return b.awaitChange(before), nil
+}
+
+// Paste simulates Ctrl+V using ydotool (evdev keycodes 29 = LEFTCTRL, 47 = V).
+func (b *waylandBackend) Paste() error {
+	return exec.Command("ydotool", "key", "29:1", "47:1", "47:0", "29:0").Run()
+}
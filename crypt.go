@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Encrypted files are encMagic, a key kind byte, a salt (all zero for
+// keyring keys), the nonce, then the XChaCha20-Poly1305 ciphertext. The
+// header is authenticated along with the content.
+const (
+	encMagic   = "CIENC1"
+	saltSize   = 16
+	headerSize = len(encMagic) + 1 + saltSize + chacha20poly1305.NonceSizeX
+)
+
+// Key kinds: a random key kept in the system keyring, or one derived from
+// the passphrase in passphraseEnv with Argon2id.
+const (
+	keyFromKeyring    byte = 'k'
+	keyFromPassphrase byte = 'p'
+)
+
+// passphraseEnv names the environment variable that, when set, supplies
+// the passphrase used instead of the system keyring.
+const passphraseEnv = "CLIPBOARD_ISLAND_PASSPHRASE"
+
+// Argon2id parameters, the second recommended option of RFC 9106.
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+)
+
+// errKeyNotFound is returned by keyring.Get when no key is stored yet.
+var errKeyNotFound = errors.New("no key in keyring")
+
+// errDecrypt hides why authentication failed; the causes are
+// indistinguishable by design.
+var errDecrypt = errors.New("wrong key or corrupted data")
+
+// keyring stores the history key outside the data directory: the macOS
+// Keychain or the Secret Service on Linux. Tests use an in-memory one.
+type keyring interface {
+	Get() ([]byte, error)
+	Set(key []byte) error
+}
+
+// cryptor seals and opens persisted files. Keys are fetched or derived on
+// first use and cached, one per key kind and salt.
+type cryptor struct {
+	ring       keyring // nil when the platform has none
+	passphrase string
+
+	aeads    map[string]cipher.AEAD
+	sealSalt []byte // Salt for passphrase keys sealed this session
+}
+
+func newCryptor(ring keyring, passphrase string) *cryptor {
+	return &cryptor{ring: ring, passphrase: passphrase, aeads: make(map[string]cipher.AEAD)}
+}
+
+// isEncrypted reports whether data was written by cryptor.seal.
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encMagic))
+}
+
+// seal encrypts plain with the passphrase key if one is configured, and
+// with the keyring key (created on first use) otherwise.
+func (c *cryptor) seal(plain []byte) ([]byte, error) {
+	kind, salt := keyFromKeyring, make([]byte, saltSize)
+	if c.passphrase != "" {
+		if c.sealSalt == nil {
+			c.sealSalt = make([]byte, saltSize)
+			rand.Read(c.sealSalt) // crypto/rand never fails on supported platforms
+		}
+		kind, salt = keyFromPassphrase, c.sealSalt
+	}
+	aead, err := c.aead(kind, salt, true)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, headerSize, headerSize+len(plain)+aead.Overhead())
+	n := copy(header, encMagic)
+	header[n] = kind
+	copy(header[n+1:], salt)
+	nonce := header[headerSize-chacha20poly1305.NonceSizeX:]
+	rand.Read(nonce)
+	return aead.Seal(header, nonce, plain, header), nil
+}
+
+// open decrypts data written by seal, with whichever key it names.
+func (c *cryptor) open(data []byte) ([]byte, error) {
+	if len(data) < headerSize || !isEncrypted(data) {
+		return nil, errors.New("not an encrypted file")
+	}
+	n := len(encMagic)
+	kind, salt := data[n], data[n+1:n+1+saltSize]
+	aead, err := c.aead(kind, salt, false)
+	if err != nil {
+		return nil, err
+	}
+	header := data[:headerSize]
+	plain, err := aead.Open(nil, header[headerSize-chacha20poly1305.NonceSizeX:], data[headerSize:], header)
+	if err != nil {
+		return nil, errDecrypt
+	}
+	return plain, nil
+}
+
+// aead returns the cipher for a key kind and salt. create allows storing
+// a fresh keyring key when there is none yet.
+func (c *cryptor) aead(kind byte, salt []byte, create bool) (cipher.AEAD, error) {
+	id := string(kind) + string(salt)
+	if aead, ok := c.aeads[id]; ok {
+		return aead, nil
+	}
+	var key []byte
+	switch kind {
+	case keyFromPassphrase:
+		if c.passphrase == "" {
+			return nil, fmt.Errorf("encrypted with a passphrase; set %s", passphraseEnv)
+		}
+		key = argon2.IDKey([]byte(c.passphrase), salt, argonTime, argonMemory, argonThreads, chacha20poly1305.KeySize)
+	case keyFromKeyring:
+		var err error
+		if key, err = c.keyringKey(create); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown key kind %q", kind)
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	c.aeads[id] = aead
+	return aead, nil
+}
+
+// keyringKey reads the key from the keyring, storing a new random one if
+// there is none and create is set.
+func (c *cryptor) keyringKey(create bool) ([]byte, error) {
+	if c.ring == nil {
+		return nil, fmt.Errorf("no system keyring available; set %s", passphraseEnv)
+	}
+	key, err := c.ring.Get()
+	if errors.Is(err, errKeyNotFound) && create {
+		key = make([]byte, chacha20poly1305.KeySize)
+		rand.Read(key)
+		if err := c.ring.Set(key); err != nil {
+			return nil, fmt.Errorf("store key in keyring: %w", err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read key from keyring: %w", err)
+	}
+	if len(key) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("keyring key has %d bytes, want %d", len(key), chacha20poly1305.KeySize)
+	}
+	return key, nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memKeyring is an in-memory keyring for tests.
+type memKeyring struct {
+	key []byte
+}
+
+func (k *memKeyring) Get() ([]byte, error) {
+	if k.key == nil {
+		return nil, errKeyNotFound
+	}
+	return k.key, nil
+}
+
+func (k *memKeyring) Set(key []byte) error {
+	k.key = bytes.Clone(key)
+	return nil
+}
+
+// TestCryptor_KeyringRoundTrip verifies sealing creates a keyring key,
+// opening with it restores the data, and tampering is detected.
+func TestCryptor_KeyringRoundTrip(t *testing.T) {
+	ring := &memKeyring{}
+	sealed, err := newCryptor(ring, "").seal([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ring.key == nil || !isEncrypted(sealed) || bytes.Contains(sealed, []byte("hello")) {
+		t.Fatalf("expected a stored key and opaque output, got %q", sealed)
+	}
+
+	plain, err := newCryptor(ring, "").open(sealed)
+	if err != nil || string(plain) != "hello" {
+		t.Fatalf("open = %q, %v", plain, err)
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := newCryptor(ring, "").open(sealed); !errors.Is(err, errDecrypt) {
+		t.Errorf("expected errDecrypt for tampered data, got %v", err)
+	}
+	if _, err := newCryptor(&memKeyring{}, "").open(sealed); err == nil {
+		t.Error("expected error opening without the key")
+	}
+}
+
+// TestCryptor_Passphrase verifies passphrase-sealed data needs the same
+// passphrase, and a clear error without one.
+func TestCryptor_Passphrase(t *testing.T) {
+	sealed, err := newCryptor(nil, "correct horse").seal([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := newCryptor(nil, "correct horse").open(sealed); err != nil || string(plain) != "hello" {
+		t.Fatalf("open = %q, %v", plain, err)
+	}
+	if _, err := newCryptor(nil, "battery staple").open(sealed); !errors.Is(err, errDecrypt) {
+		t.Errorf("expected errDecrypt for a wrong passphrase, got %v", err)
+	}
+	if _, err := newCryptor(nil, "").open(sealed); err == nil || !strings.Contains(err.Error(), passphraseEnv) {
+		t.Errorf("expected error naming %s, got %v", passphraseEnv, err)
+	}
+}
+
+// TestPersist_EncryptionMigration verifies turning encryption on rewrites
+// the plaintext history and images encrypted, they load back, and turning
+// it off again restores plaintext.
+func TestPersist_EncryptionMigration(t *testing.T) {
+	dir := t.TempDir()
+	ring := &memKeyring{}
+	app := newPersistentApp(t, dir, true)
+	app.keyring = ring
+	app.addImageItem(testImage(1))
+	app.addItem("customer data")
+	app.flushSave()
+
+	settings := app.GetSettings()
+	settings.EncryptHistory = true
+	if err := app.UpdateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	app.flushSave()
+
+	data, err := os.ReadFile(app.historyPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(data) || bytes.Contains(data, []byte("customer data")) {
+		t.Fatal("expected the history file to be encrypted")
+	}
+	blob, err := os.ReadFile(filepath.Join(dir, "images", app.history[1].ImageHash+".png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(blob) {
+		t.Error("expected the image blob to be encrypted")
+	}
+	if status := app.GetStorageStatus(); !status.Encrypted || status.Error != "" {
+		t.Errorf("unexpected storage status %+v", status)
+	}
+
+	restored := newPersistentApp(t, dir, true)
+	restored.keyring = ring
+	restored.settings.EncryptHistory = true
+	restored.loadHistory()
+	if len(restored.history) != 2 || restored.history[0].Text != "customer data" ||
+		restored.history[1].ImageData != app.history[1].ImageData {
+		t.Fatalf("unexpected restored history: %d items", len(restored.history))
+	}
+
+	restored.settings.EncryptHistory = false
+	restored.scheduleSave()
+	restored.flushSave()
+	if data, _ := os.ReadFile(restored.historyPath()); isEncrypted(data) {
+		t.Error("expected plaintext history after turning encryption off")
+	}
+	if blob, _ := os.ReadFile(filepath.Join(dir, "images", app.history[1].ImageHash+".png")); isEncrypted(blob) {
+		t.Error("expected plaintext image after turning encryption off")
+	}
+}
+
+// TestPersist_EncryptionConvertsBackups verifies turning encryption on
+// seals the backups and the set-aside file written before, owner-only, and
+// the sealed backups still serve as a fallback.
+func TestPersist_EncryptionConvertsBackups(t *testing.T) {
+	dir := t.TempDir()
+	ring := &memKeyring{}
+	app := newPersistentApp(t, dir, true)
+	app.keyring = ring
+	clk := newFakeClock()
+	app.clock = clk
+	app.addItem("customer data")
+	app.flushSave()
+	app.addItem("more")
+	app.flushSave()
+	clk.Advance(historyBackupInterval)
+	path := app.historyPath()
+	if err := os.WriteFile(path+".corrupt", []byte(`{"items": [{"text": "customer data"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := app.GetSettings()
+	settings.EncryptHistory = true
+	if err := app.UpdateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	app.flushSave()
+
+	for _, file := range []string{backupPath(path, 1), backupPath(path, 2), path + ".corrupt"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isEncrypted(data) || bytes.Contains(data, []byte("customer data")) {
+			t.Errorf("%s: expected it encrypted", filepath.Base(file))
+		}
+		if info, _ := os.Stat(file); info.Mode().Perm() != 0600 {
+			t.Errorf("%s: got mode %v", filepath.Base(file), info.Mode().Perm())
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	restored := newPersistentApp(t, dir, true)
+	restored.keyring = ring
+	restored.settings.EncryptHistory = true
+	restored.loadHistory()
+	if len(restored.history) != 2 || restored.history[1].Text != "customer data" {
+		t.Errorf("expected the sealed backup to load, got %+v", restored.history)
+	}
+}
+
+// TestPersist_DecryptFailureBlocksSaving verifies a history the key cannot
+// open is reported and left untouched.
+func TestPersist_DecryptFailureBlocksSaving(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, true)
+	app.keyring = &memKeyring{}
+	app.settings.EncryptHistory = true
+	app.addItem("secret notes")
+	app.flushSave()
+	before, err := os.ReadFile(app.historyPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newPersistentApp(t, dir, true)
+	restored.keyring = &memKeyring{key: bytes.Repeat([]byte{7}, 32)}
+	restored.settings.EncryptHistory = true
+	restored.loadHistory()
+	if status := restored.GetStorageStatus(); status.Error == "" {
+		t.Fatal("expected a storage error")
+	}
+	if len(restored.history) != 0 {
+		t.Errorf("expected no items, got %d", len(restored.history))
+	}
+
+	restored.addItem("new clip")
+	restored.flushSave()
+	after, _ := os.ReadFile(restored.historyPath())
+	if !bytes.Equal(before, after) {
+		t.Error("history file was overwritten after a failed load")
+	}
+}
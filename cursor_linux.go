@@ -0,0 +1,32 @@
+package main
+
+/*
+#cgo LDFLAGS: -lX11
+#include <X11/Xlib.h>
+
+static int pointerAndScreen(int *mx, int *my, int *sw, int *sh) {
+    Display *d = XOpenDisplay(NULL);
+    if (!d) return 0;
+    Window root, child;
+    int wx, wy;
+    unsigned int mask;
+    XQueryPointer(d, DefaultRootWindow(d), &root, &child, mx, my, &wx, &wy, &mask);
+    Screen *s = DefaultScreenOfDisplay(d);
+    *sw = WidthOfScreen(s);
+    *sh = HeightOfScreen(s);
+    XCloseDisplay(d);
+    return 1;
+}
+*/
+import "C"
+
+// cursorAndScreen returns the mouse position and root window size in X11
+// pixels. X11 has no per-screen scale factor, so scale is always 1.
+// Without an X server (pure Wayland) it centres on a 1920x1080 screen.
+func cursorAndScreen() (cx, cy, sw, sh, scale int) {
+	var mx, my, screenW, screenH C.int
+	if C.pointerAndScreen(&mx, &my, &screenW, &screenH) == 0 {
+		return 960, 540, 1920, 1080, 1
+	}
+	return int(mx), int(my), int(screenW), int(screenH), 1
+}
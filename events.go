@@ -0,0 +1,64 @@
+package main
+
+import "github.com/wailsapp/wails/v3/pkg/application"
+
+// EventHistoryChanged is emitted after every mutation of the history.
+const EventHistoryChanged = "history:changed"
+
+func init() {
+	application.RegisterEvent[HistoryChange](EventHistoryChanged)
+	application.RegisterEvent[Settings](EventSettingsChanged)
+	application.RegisterEvent[[]HotkeyStatus](EventHotkeysChanged)
+	application.RegisterEvent[StorageStatus](EventStorageChanged)
+	application.RegisterEvent[PauseStatus](EventPauseChanged)
+}
+
+// HistoryChange is the payload of EventHistoryChanged. Revisions increase by
+// one per change, so a frontend that sees a gap knows it missed an event and
+// should re-fetch with GetHistory.
+type HistoryChange struct {
+	Revision uint64     `json:"revision"`
+	Upserted []ClipItem `json:"upserted,omitempty"` // Items added or modified
+	Removed  []string   `json:"removed,omitempty"`  // IDs no longer in history
+	Order    []string   `json:"order"`              // All IDs, front to back, after the change
+}
+
+// historyIDs returns the IDs of all items in display order.
+// Caller must hold a.mu.
+func (a *App) historyIDs() []string {
+	items := a.displayHistory()
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+// historyChanged bumps the revision and describes a mutation: upserted are the
+// items the caller added or modified, and any ID in prevIDs that is no longer
+// in history (deleted or evicted) is reported as removed.
+// Caller must hold a.mu.
+func (a *App) historyChanged(prevIDs []string, upserted ...ClipItem) HistoryChange {
+	a.revision++
+	change := HistoryChange{Revision: a.revision, Upserted: upserted, Order: a.historyIDs()}
+
+	current := make(map[string]bool, len(change.Order))
+	for _, id := range change.Order {
+		current[id] = true
+	}
+	for _, id := range prevIDs {
+		if !current[id] {
+			change.Removed = append(change.Removed, id)
+		}
+	}
+	return change
+}
+
+// publish emits a history change to the frontend. Call it without holding a.mu.
+// Sensitive items are masked on the way out.
+func (a *App) publish(change HistoryChange) {
+	maskItems(change.Upserted)
+	if a.emit != nil {
+		a.emit(EventHistoryChanged, change)
+	}
+}
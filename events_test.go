@@ -0,0 +1,110 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordEvents attaches an emitter to app that collects history changes.
+func recordEvents(app *App) func() []HistoryChange {
+	var mu sync.Mutex
+	var changes []HistoryChange
+	app.emit = func(name string, data any) {
+		if name != EventHistoryChanged {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, data.(HistoryChange))
+	}
+	return func() []HistoryChange {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(changes)
+	}
+}
+
+// TestHistoryChanged_AddItem verifies adding text emits an upsert with the new order.
+func TestHistoryChanged_AddItem(t *testing.T) {
+	app := &App{}
+	events := recordEvents(app)
+
+	app.addItem("first")
+	app.addItem("second")
+
+	changes := events()
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(changes))
+	}
+	last := changes[1]
+	if last.Revision != 2 {
+		t.Errorf("expected revision 2, got %d", last.Revision)
+	}
+	if len(last.Upserted) != 1 || last.Upserted[0].Text != "second" {
+		t.Errorf("expected upsert of 'second', got %+v", last.Upserted)
+	}
+	if !slices.Equal(last.Order, app.historyIDs()) {
+		t.Errorf("order %v does not match history %v", last.Order, app.historyIDs())
+	}
+}
+
+// TestHistoryChanged_SkippedAddEmitsNothing verifies no event when nothing changed.
+func TestHistoryChanged_SkippedAddEmitsNothing(t *testing.T) {
+	app := &App{}
+	app.addItem("item")
+	events := recordEvents(app)
+
+	app.addItem("   ")
+	app.addItem(strings.Repeat("x", defaultSettings().MaxTextLength+1))
+
+	if n := len(events()); n != 0 {
+		t.Errorf("expected no changes, got %d", n)
+	}
+}
+
+// TestHistoryChanged_Eviction verifies items dropped by the cap are reported as removed.
+func TestHistoryChanged_Eviction(t *testing.T) {
+	app := &App{}
+	for i := 0; i < 30; i++ {
+		app.addItem(string(rune('a' + i)))
+	}
+	oldest := app.history[29].ID
+	events := recordEvents(app)
+
+	app.addItem("overflow")
+
+	changes := events()
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d", len(changes))
+	}
+	if !slices.Equal(changes[0].Removed, []string{oldest}) {
+		t.Errorf("expected %q evicted, got %v", oldest, changes[0].Removed)
+	}
+}
+
+// TestHistoryChanged_PinAndDelete verifies TogglePin and DeleteItem emit changes.
+func TestHistoryChanged_PinAndDelete(t *testing.T) {
+	app := &App{}
+	app.addItem("item")
+	id := app.history[0].ID
+	events := recordEvents(app)
+
+	app.TogglePin(id)
+	app.DeleteItem(id)
+
+	changes := events()
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(changes))
+	}
+	if len(changes[0].Upserted) != 1 || !changes[0].Upserted[0].Pinned {
+		t.Errorf("expected pinned upsert, got %+v", changes[0].Upserted)
+	}
+	if !slices.Equal(changes[1].Removed, []string{id}) || len(changes[1].Order) != 0 {
+		t.Errorf("expected removal of %q, got %+v", id, changes[1])
+	}
+	if changes[1].Revision != changes[0].Revision+1 {
+		t.Errorf("expected consecutive revisions, got %d then %d", changes[0].Revision, changes[1].Revision)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// excludeReason returns why the current clipboard content must not be
+// captured, or "" if it may be: the copying app marked it concealed or
+// transient, or it came from one of settings.ExcludedApps. The source app
+// is only looked up when there is a list to check it against.
+func (a *App) excludeReason(settings Settings) string {
+	if a.clip.Read(FormatConcealed) != nil {
+		return "marked concealed"
+	}
+	if a.clip.Read(FormatTransient) != nil {
+		return "marked transient"
+	}
+	if len(settings.ExcludedApps) > 0 {
+		source := a.clip.SourceApp()
+		if source != "" && slices.ContainsFunc(settings.ExcludedApps, func(app string) bool {
+			return strings.EqualFold(strings.TrimSpace(app), source)
+		}) {
+			return "copied from excluded app " + source
+		}
+	}
+	return ""
+}
+
+// compilePatterns compiles Settings.ExcludePatterns, reporting the first
+// that is not a valid regular expression.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("excludePatterns[%d]: %w", i, err)
+		}
+		compiled[i] = re
+	}
+	return compiled, nil
+}
+
+// compileExcludes compiles the patterns excludedText checks, once per
+// settings change. Settings.validate has rejected invalid ones already, so
+// a failure here only drops the patterns, with a log.
+func compileExcludes(patterns []string) []*regexp.Regexp {
+	compiled, err := compilePatterns(patterns)
+	if err != nil {
+		log.Printf("[clipboard] ignoring exclude patterns: %v", err)
+	}
+	return compiled
+}
+
+// excludedText returns why text must not be captured because it matches
+// one of patterns, or "".
+func excludedText(patterns []*regexp.Regexp, text string) string {
+	for i, re := range patterns {
+		if re.MatchString(text) {
+			return fmt.Sprintf("matches excludePatterns[%d]", i)
+		}
+	}
+	return ""
+}
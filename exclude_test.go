@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+// TestPollClipboard_SkipsMarkedClips verifies clips flagged concealed or
+// transient are not captured, even when the marker has no content.
+func TestPollClipboard_SkipsMarkedClips(t *testing.T) {
+	for _, marker := range []ClipFormat{FormatConcealed, FormatTransient} {
+		fake := newFakeBackend()
+		app := NewApp(fake)
+		state := &watchState{lastCount: fake.ChangeCount()}
+
+		fake.Write(map[ClipFormat][]byte{FormatText: []byte("hunter2"), marker: {}})
+		app.pollClipboard(state)
+		if len(app.history) != 0 {
+			t.Errorf("%s: expected clip to be skipped, got %q", marker, app.history[0].Text)
+		}
+
+		fake.copyText("hunter2")
+		app.pollClipboard(state)
+		if len(app.history) != 1 {
+			t.Errorf("%s: expected the same text to be captured without the marker", marker)
+		}
+	}
+}
+
+// TestPollClipboard_SkipsExcludedApps verifies clips copied from an app on
+// the deny list are skipped, matching the app name case-insensitively.
+func TestPollClipboard_SkipsExcludedApps(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	app.settings.ExcludedApps = []string{"com.1password.1Password", "KeePassXC"}
+	state := &watchState{lastCount: fake.ChangeCount()}
+
+	fake.source = "keepassxc"
+	fake.copyText("s3cret")
+	app.pollClipboard(state)
+	if len(app.history) != 0 {
+		t.Fatalf("expected clip from excluded app to be skipped, got %q", app.history[0].Text)
+	}
+
+	fake.source = "org.gnome.TextEditor"
+	fake.copyText("notes")
+	app.pollClipboard(state)
+	if len(app.history) != 1 || app.history[0].Text != "notes" {
+		t.Errorf("expected clip from other app to be captured, got %+v", app.history)
+	}
+}
+
+// TestPollClipboard_SkipsExcludedPatterns verifies text matching an
+// exclusion pattern is skipped, including file lists turned into paths.
+func TestPollClipboard_SkipsExcludedPatterns(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	settings := app.GetSettings()
+	settings.ExcludePatterns = []string{`^\d{4}( ?\d{4}){3}$`, `(?i)/\.ssh/`}
+	if err := app.UpdateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	state := &watchState{lastCount: fake.ChangeCount()}
+
+	fake.copyText("4111 1111 1111 1111")
+	app.pollClipboard(state)
+	fake.Write(map[ClipFormat][]byte{FormatFiles: []byte("file:///home/me/.ssh/id_ed25519\n")})
+	app.pollClipboard(state)
+	if len(app.history) != 0 {
+		t.Fatalf("expected matching clips to be skipped, got %+v", app.history)
+	}
+
+	fake.copyText("4111")
+	app.pollClipboard(state)
+	if len(app.history) != 1 {
+		t.Errorf("expected non-matching text to be captured, got %d items", len(app.history))
+	}
+}
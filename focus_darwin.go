@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// frontmostApp returns the PID of the frontmost process.
+func frontmostApp() (string, error) {
+	out, err := exec.Command("osascript", "-e",
+		`tell application "System Events" to get unix id of first process whose frontmost is true`,
+	).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// activateApp brings the process with the given PID to the front.
+func activateApp(pid string) error {
+	script := `tell application "System Events" to set frontmost of (first process whose unix id is ` + pid + `) to true`
+	return exec.Command("osascript", "-e", script).Run()
+}
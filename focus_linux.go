@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// errNoFocusControl is returned when no X server is reachable; Wayland does
+// not let clients query or change another client's focus.
+var errNoFocusControl = errors.New("focus tracking requires X11 (DISPLAY is not set)")
+
+// frontmostApp returns the X11 window ID of the active window.
+func frontmostApp() (string, error) {
+	if os.Getenv("DISPLAY") == "" {
+		return "", errNoFocusControl
+	}
+	out, err := exec.Command("xdotool", "getactivewindow").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// activateApp focuses the X11 window with the given ID.
+func activateApp(windowID string) error {
+	if os.Getenv("DISPLAY") == "" {
+		return errNoFocusControl
+	}
+	return exec.Command("xdotool", "windowactivate", "--sync", windowID).Run()
+}
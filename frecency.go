@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// Sort modes for Settings.SortMode.
+const (
+	SortRecent   = "recent"   // Most recently copied first
+	SortFrecency = "frecency" // Most used first, with use decaying over time
+)
+
+// frecencyHalfLife is how long it takes an item's usage weight to halve.
+const frecencyHalfLife = 72 * time.Hour
+
+// frecency scores item by how often and how recently it was used: each use
+// (plus the initial copy) counts 1, halved for every frecencyHalfLife since
+// the item was last used.
+func frecency(item ClipItem, now time.Time) float64 {
+	age := now.Sub(item.LastUsedAt)
+	if age < 0 {
+		age = 0
+	}
+	decay := math.Exp2(-float64(age) / float64(frecencyHalfLife))
+	return float64(item.UseCount+1) * decay
+}
+
+// displayHistory returns a copy of the history in the order the settings'
+// sort mode shows it; ties keep history order.
+// Caller must hold a.mu.
+func (a *App) displayHistory() []ClipItem {
+	items := make([]ClipItem, len(a.history)) // Non-nil, so the frontend gets [] rather than null
+	copy(items, a.history)
+	if a.settings.withDefaults().SortMode != SortFrecency {
+		return items
+	}
+	now := time.Now()
+	scores := make(map[string]float64, len(items))
+	for _, item := range items {
+		scores[item.ID] = frecency(item, now)
+	}
+	slices.SortStableFunc(items, func(x, y ClipItem) int {
+		return cmpDesc(scores[x.ID], scores[y.ID])
+	})
+	return items
+}
+
+// cmpDesc orders larger values first.
+func cmpDesc(x, y float64) int {
+	switch {
+	case x > y:
+		return -1
+	case x < y:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestFrecency_Decay verifies usage weight halves every half-life.
+func TestFrecency_Decay(t *testing.T) {
+	now := time.Now()
+	item := ClipItem{UseCount: 3, LastUsedAt: now}
+	if got := frecency(item, now); got != 4 {
+		t.Errorf("expected 4 right after use, got %v", got)
+	}
+	item.LastUsedAt = now.Add(-frecencyHalfLife)
+	if got := frecency(item, now); got != 2 {
+		t.Errorf("expected 2 after one half-life, got %v", got)
+	}
+}
+
+// TestGetHistory_FrecencyOrder verifies pasted items move up in frecency
+// mode but not in the default recency mode.
+func TestGetHistory_FrecencyOrder(t *testing.T) {
+	clip := newFakeBackend()
+	app := NewApp(clip)
+	app.addItem("daily snippet")
+	app.addItem("one-off")
+	app.SelectItem(app.history[1].ID)
+	<-clip.pasted
+
+	if h := app.GetHistory(); h[0].Text != "one-off" {
+		t.Errorf("recent mode: expected one-off first, got %q", h[0].Text)
+	}
+
+	events := recordEvents(app)
+	settings := app.GetSettings()
+	settings.SortMode = SortFrecency
+	if err := app.UpdateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if h := app.GetHistory(); h[0].Text != "daily snippet" || h[0].UseCount != 1 {
+		t.Errorf("frecency mode: expected daily snippet first with 1 use, got %+v", h[0])
+	}
+	changes := events()
+	if len(changes) != 1 || changes[0].Order[0] != app.GetHistory()[0].ID {
+		t.Errorf("expected a change carrying the new order, got %+v", changes)
+	}
+}
+
+// TestSelectItem_RecordsUse verifies pasting bumps the use count and
+// publishes the updated item.
+func TestSelectItem_RecordsUse(t *testing.T) {
+	clip := newFakeBackend()
+	app := NewApp(clip)
+	app.addItem("snippet")
+	events := recordEvents(app)
+
+	app.SelectItem(app.history[0].ID)
+	<-clip.pasted
+
+	if app.history[0].UseCount != 1 {
+		t.Errorf("expected use count 1, got %d", app.history[0].UseCount)
+	}
+	changes := events()
+	if len(changes) != 1 || len(changes[0].Upserted) != 1 || changes[0].Upserted[0].UseCount != 1 {
+		t.Errorf("expected one change upserting the used item, got %+v", changes)
+	}
+}
@@ -4,21 +4,31 @@ import { App } from "../bindings/changeme";
 const island = document.getElementById("island");
 const islandBody = document.getElementById("island-body");
 const islandCount = document.getElementById("island-count");
+const islandNotice = document.getElementById("island-notice");
+const islandTitle = document.getElementById("island-title");
+const islandSearch = document.getElementById("island-search");
+const islandPaused = document.getElementById("island-paused");
 
 let isOpen = false;
 let selectedIndex = -1;
 let allItems = [];
+let revision = null; // Last applied history:changed revision, null after a full fetch
+const rowsById = new Map();
+let query = ""; // Type-to-filter text; empty shows the whole history
+let searchSeq = 0; // Drops responses to searches that were superseded
+const highlights = new Map(); // Item ID → match ranges of the current search
 
 // ── Render clipboard history ─────────────────────────────────────────────────
 function renderHistory(items) {
   // Clear current content
   islandBody.innerHTML = "";
+  rowsById.clear();
   allItems = items;
 
   if (items.length === 0) {
     const empty = document.createElement("div");
     empty.className = "empty-state";
-    empty.textContent = "Copy text or images to get started";
+    empty.textContent = query ? "No matches" : "Copy text or images to get started";
     islandBody.appendChild(empty);
     islandCount.textContent = "0";
     selectedIndex = -1;
@@ -30,74 +40,226 @@ function renderHistory(items) {
   list.className = "clip-list";
 
   // Add each item
-  items.forEach((item, index) => {
-    const row = document.createElement("div");
-    row.className = "clip-row" + (item.pinned ? " pinned" : "");
-    row.dataset.index = index;
-
-    // Content: text or image
-    if (item.type === "image") {
-      const img = document.createElement("img");
-      img.className = "clip-image";
-      img.src = item.imageData || item.ImageData;
-      img.alt = "Clipboard image";
-      row.appendChild(img);
-    } else {
-      const text = document.createElement("div");
-      text.className = "clip-text";
-      text.textContent = item.text || item.Text;
-      row.appendChild(text);
+  items.forEach((item) => {
+    const row = createRow(item);
+    rowsById.set(item.id, row);
+    list.appendChild(row);
+  });
+
+  islandBody.appendChild(list);
+  islandCount.textContent = String(items.length);
+
+  // Select first item by default
+  if (selectedIndex < 0 || selectedIndex >= items.length) {
+    selectedIndex = 0;
+  }
+  updateSelection(selectedIndex);
+}
+
+// ── Content kinds (see classify.go) ──────────────────────────────────────────
+const kindLabels = {
+  url: "URL",
+  email: "Email",
+  path: "Path",
+  color: "Color",
+  json: "JSON",
+  code: "Code",
+  phone: "Phone",
+  number: "Number",
+  uuid: "UUID",
+};
+const openableKinds = new Set(["url", "email", "path"]);
+
+// ── Build one row ─────────────────────────────────────────────────────────────
+// Handlers look the item up by ID at event time, so rows stay valid when
+// other items are inserted or removed around them.
+function createRow(item) {
+  const row = document.createElement("div");
+  row.className = "clip-row" + (item.pinned ? " pinned" : "");
+  row.dataset.id = item.id;
+
+  // Content: text or image
+  if (item.type === "image") {
+    const img = document.createElement("img");
+    img.className = "clip-image";
+    img.src = item.imageData || item.ImageData;
+    img.alt = "Clipboard image";
+    row.appendChild(img);
+  } else {
+    if (item.kind === "color") {
+      const swatch = document.createElement("span");
+      swatch.className = "clip-swatch";
+      swatch.style.background = item.text;
+      row.appendChild(swatch);
+    }
+    const text = document.createElement("div");
+    text.className = "clip-text";
+    renderText(text, item.text || item.Text, highlights.get(item.id));
+    row.appendChild(text);
+    if (item.sensitive) {
+      const badge = document.createElement("span");
+      badge.className = "clip-badge sensitive";
+      badge.textContent = item.secretKind;
+      row.appendChild(badge);
+    } else if (item.kind) {
+      const badge = document.createElement("span");
+      badge.className = "clip-badge";
+      badge.textContent = item.language || kindLabels[item.kind] || item.kind;
+      row.appendChild(badge);
     }
+  }
+
+  // Action buttons container
+  const actions = document.createElement("div");
+  actions.className = "clip-actions";
 
-    // Action buttons container
-    const actions = document.createElement("div");
-    actions.className = "clip-actions";
+  // Pin button (☆/★)
+  const pinBtn = document.createElement("button");
+  pinBtn.className = "clip-btn pin-btn";
+  pinBtn.textContent = (item.pinned || item.Pinned) ? "★" : "☆";
+  pinBtn.title = (item.pinned || item.Pinned) ? "Unpin" : "Pin";
+  pinBtn.addEventListener("click", (e) => {
+    e.stopPropagation();
+    togglePin(item.id);
+  });
+
+  // Delete button (×)
+  const delBtn = document.createElement("button");
+  delBtn.className = "clip-btn del-btn";
+  delBtn.textContent = "×";
+  delBtn.title = "Delete";
+  delBtn.addEventListener("click", (e) => {
+    e.stopPropagation();
+    deleteItem(item.id);
+  });
 
-    // Pin button (☆/★)
-    const pinBtn = document.createElement("button");
-    pinBtn.className = "clip-btn pin-btn";
-    pinBtn.textContent = (item.pinned || item.Pinned) ? "★" : "☆";
-    pinBtn.title = (item.pinned || item.Pinned) ? "Unpin" : "Pin";
-    pinBtn.addEventListener("click", (e) => {
+  // Open button (↗) for links, addresses and paths
+  if (openableKinds.has(item.kind)) {
+    const openBtn = document.createElement("button");
+    openBtn.className = "clip-btn open-btn";
+    openBtn.textContent = "↗";
+    openBtn.title = item.kind === "path" ? "Open file" : "Open";
+    openBtn.addEventListener("click", (e) => {
       e.stopPropagation();
-      togglePin(index);
+      openItem(item.id);
     });
+    actions.appendChild(openBtn);
+  }
 
-    // Delete button (×)
-    const delBtn = document.createElement("button");
-    delBtn.className = "clip-btn del-btn";
-    delBtn.textContent = "×";
-    delBtn.title = "Delete";
-    delBtn.addEventListener("click", (e) => {
+  // Reveal button (◉/◎) for masked secrets
+  if (item.sensitive) {
+    const revealBtn = document.createElement("button");
+    revealBtn.className = "clip-btn reveal-btn";
+    revealBtn.textContent = item.masked ? "◉" : "◎";
+    revealBtn.title = item.masked ? "Reveal" : "Hide";
+    revealBtn.addEventListener("click", (e) => {
       e.stopPropagation();
-      deleteItem(index);
+      toggleReveal(item);
     });
+    actions.appendChild(revealBtn);
+  }
 
-    actions.appendChild(pinBtn);
-    actions.appendChild(delBtn);
-
-    row.appendChild(actions);
-    list.appendChild(row);
+  actions.appendChild(pinBtn);
+  actions.appendChild(delBtn);
+  row.appendChild(actions);
 
-    // Click on row to paste
-    row.addEventListener("click", () => {
-      selectAndPaste(index);
-    });
+  // Click on row to paste (Shift/Alt pick the paste mode, see pasteModeFor)
+  row.addEventListener("click", (e) => {
+    selectAndPaste(indexOfId(item.id), pasteModeFor(e));
+  });
 
-    // Mouse hover updates selection
-    row.addEventListener("mouseenter", () => {
-      updateSelection(index);
-    });
+  // Mouse hover updates selection
+  row.addEventListener("mouseenter", () => {
+    updateSelection(indexOfId(item.id));
   });
 
-  islandBody.appendChild(list);
-  islandCount.textContent = String(items.length);
+  return row;
+}
 
-  // Select first item by default
-  if (selectedIndex < 0 || selectedIndex >= items.length) {
-    selectedIndex = 0;
+// Fills el with text, wrapping the match ranges (UTF-16 offsets from
+// SearchHistory) in <mark>.
+function renderText(el, text, ranges) {
+  if (!ranges || ranges.length === 0) {
+    el.textContent = text;
+    return;
   }
-  updateSelection(selectedIndex);
+  let pos = 0;
+  for (const { start, end } of ranges) {
+    el.append(text.slice(pos, start));
+    const mark = document.createElement("mark");
+    mark.textContent = text.slice(start, end);
+    el.append(mark);
+    pos = end;
+  }
+  el.append(text.slice(pos));
+}
+
+function indexOfId(id) {
+  return allItems.findIndex((item) => item.id === id);
+}
+
+// ── Apply an incremental history change ──────────────────────────────────────
+function applyChange(change) {
+  // The transform picker owns the body; catch up when it closes.
+  if (transforming) {
+    revision = null;
+    return;
+  }
+
+  // Search results are ranked, not in history order; just search again.
+  if (query) {
+    runSearch();
+    return;
+  }
+
+  // A gap in revisions means we missed an event; start over from the backend.
+  if (revision !== null && change.revision !== revision + 1) {
+    refreshHistory();
+    return;
+  }
+  revision = change.revision;
+
+  const byId = new Map(allItems.map((item) => [item.id, item]));
+  for (const id of change.removed || []) {
+    byId.delete(id);
+  }
+  for (const item of change.upserted || []) {
+    byId.set(item.id, item);
+  }
+
+  const items = change.order.map((id) => byId.get(id));
+  if (items.some((item) => item === undefined)) {
+    refreshHistory();
+    return;
+  }
+
+  // Switching between the empty state and the list is simplest as a full render.
+  const list = islandBody.querySelector(".clip-list");
+  if (!list || items.length === 0) {
+    renderHistory(items);
+    return;
+  }
+
+  const selectedId = allItems[selectedIndex]?.id;
+  allItems = items;
+
+  for (const id of change.removed || []) {
+    rowsById.get(id)?.remove();
+    rowsById.delete(id);
+  }
+  for (const item of change.upserted || []) {
+    const row = createRow(item);
+    rowsById.get(item.id)?.replaceWith(row);
+    rowsById.set(item.id, row);
+  }
+  // appendChild moves existing rows, so this puts every row in backend order.
+  for (const id of change.order) {
+    list.appendChild(rowsById.get(id));
+  }
+
+  islandCount.textContent = String(items.length);
+  const keep = indexOfId(selectedId);
+  updateSelection(keep >= 0 ? keep : Math.min(selectedIndex, items.length - 1));
 }
 
 // ── Update selection highlight ───────────────────────────────────────────────
@@ -120,35 +282,70 @@ function updateSelection(index) {
 }
 
 // ── Toggle pin status ─────────────────────────────────────────────────────────
-async function togglePin(index) {
+async function togglePin(id) {
   try {
-    await App.TogglePin(index);
-    await refreshHistory();
+    await App.TogglePin(id);
   } catch (err) {
     console.error("Failed to toggle pin:", err);
   }
 }
 
+// ── Reveal or hide a secret ──────────────────────────────────────────────────
+async function toggleReveal(item) {
+  try {
+    if (item.masked) {
+      await App.RevealItem(item.id);
+    } else {
+      await App.ConcealItem(item.id);
+    }
+  } catch (err) {
+    console.error("Failed to toggle reveal:", err);
+  }
+}
+
+// ── Open link, address or path ───────────────────────────────────────────────
+async function openItem(id) {
+  try {
+    await App.OpenItem(id);
+    dismiss();
+  } catch (err) {
+    console.error("Failed to open item:", err);
+  }
+}
+
 // ── Delete item ───────────────────────────────────────────────────────────────
-async function deleteItem(index) {
+async function deleteItem(id) {
   try {
-    await App.DeleteItem(index);
-    await refreshHistory();
+    await App.DeleteItem(id);
   } catch (err) {
     console.error("Failed to delete item:", err);
   }
 }
 
 // ── Select and paste item ────────────────────────────────────────────────────
-async function selectAndPaste(index) {
+// Shift pastes plain text, Alt (Option) pastes text joined into one line;
+// otherwise the default paste mode from settings applies.
+function pasteModeFor(e) {
+  if (e.shiftKey) return "plain";
+  if (e.altKey) return "singleLine";
+  return "";
+}
+
+async function selectAndPaste(index, mode = "") {
   if (index < 0 || index >= allItems.length) return;
   
   try {
     isOpen = false;
     island.classList.remove("open");
-    await App.SelectItem(index);
+    if (mode) {
+      await App.PasteItemAs(allItems[index].id, mode);
+    } else {
+      await App.SelectItem(allItems[index].id);
+    }
+    renderPasteStatus("");
   } catch (err) {
     console.error("Failed to paste:", err);
+    renderPasteStatus(err);
   }
 }
 
@@ -156,12 +353,285 @@ async function selectAndPaste(index) {
 async function refreshHistory() {
   try {
     const history = await App.GetHistory();
+    revision = null;
     renderHistory(history);
   } catch (err) {
     console.error("Failed to get history:", err);
   }
 }
 
+// ── Type-to-filter search ─────────────────────────────────────────────────────
+// "is:pinned", "is:unpinned", "is:text", "is:image" and "is:url" become
+// filters; everything else is the search text.
+function parseQuery(input) {
+  const filters = { types: [] };
+  const words = [];
+  for (const word of input.trim().split(/\s+/)) {
+    const m = /^is:(\w+)$/i.exec(word);
+    if (!m) {
+      words.push(word);
+      continue;
+    }
+    const value = m[1].toLowerCase();
+    if (value === "pinned" || value === "unpinned") {
+      filters.pinned = value === "pinned";
+    } else {
+      filters.types.push(value);
+    }
+  }
+  return { text: words.join(" "), filters };
+}
+
+async function runSearch() {
+  const seq = ++searchSeq;
+  const { text, filters } = parseQuery(query);
+  try {
+    const results = (await App.SearchHistory(text, filters)) || [];
+    if (seq !== searchSeq) return;
+    highlights.clear();
+    for (const result of results) {
+      highlights.set(result.item.id, result.matches);
+    }
+    revision = null;
+    selectedIndex = 0;
+    renderHistory(results.map((result) => result.item));
+  } catch (err) {
+    console.error("Failed to search history:", err);
+  }
+}
+
+function openSearch(initial) {
+  islandTitle.classList.add("hidden");
+  islandSearch.classList.remove("hidden");
+  islandSearch.value += initial;
+  islandSearch.focus();
+  query = islandSearch.value;
+  runSearch();
+}
+
+// Leaves search mode; refresh reloads the full history.
+function closeSearch(refresh) {
+  query = "";
+  searchSeq++;
+  highlights.clear();
+  islandSearch.value = "";
+  islandSearch.blur();
+  islandSearch.classList.add("hidden");
+  islandTitle.classList.remove("hidden");
+  if (refresh) {
+    selectedIndex = 0;
+    refreshHistory();
+  }
+}
+
+islandSearch.addEventListener("input", () => {
+  query = islandSearch.value;
+  if (query === "") {
+    closeSearch(true);
+    return;
+  }
+  runSearch();
+});
+
+// ── Transform picker ─────────────────────────────────────────────────────────
+// Tab on a text item lists the transforms; Space adds the highlighted one
+// to a chain, Enter pastes through the chain (or just the highlighted one).
+let transforms = [];
+let transforming = false;
+let transformTarget = null; // ID of the item being transformed
+let transformIndex = 0;
+let transformChain = [];
+
+App.ListTransforms()
+  .then((list) => {
+    transforms = list || [];
+  })
+  .catch((err) => console.error("Failed to list transforms:", err));
+
+function openTransforms() {
+  const item = allItems[selectedIndex];
+  if (!item || item.type !== "text" || transforms.length === 0) return;
+  transforming = true;
+  transformTarget = item.id;
+  transformIndex = 0;
+  transformChain = [];
+  renderTransforms();
+}
+
+function renderTransforms() {
+  islandBody.innerHTML = "";
+  const list = document.createElement("div");
+  list.className = "clip-list";
+  transforms.forEach((transform, i) => {
+    const row = document.createElement("div");
+    row.className = "clip-row transform-row" + (i === transformIndex ? " selected" : "");
+    const label = document.createElement("div");
+    label.className = "clip-text";
+    label.textContent = transform.label;
+    row.appendChild(label);
+    const step = transformChain.indexOf(transform.id);
+    if (step >= 0) {
+      const badge = document.createElement("span");
+      badge.className = "clip-badge";
+      badge.textContent = String(step + 1);
+      row.appendChild(badge);
+    }
+    row.addEventListener("click", () => {
+      transformIndex = i;
+      applyTransforms();
+    });
+    list.appendChild(row);
+  });
+  islandBody.appendChild(list);
+  list.children[transformIndex]?.scrollIntoView({ block: "nearest" });
+}
+
+function toggleTransform() {
+  const id = transforms[transformIndex].id;
+  const step = transformChain.indexOf(id);
+  if (step >= 0) {
+    transformChain.splice(step, 1);
+  } else {
+    transformChain.push(id);
+  }
+  renderTransforms();
+}
+
+async function applyTransforms() {
+  const chain = transformChain.length > 0 ? transformChain : [transforms[transformIndex].id];
+  const id = transformTarget;
+  closeTransforms(false);
+  try {
+    isOpen = false;
+    island.classList.remove("open");
+    await App.PasteTransformed(id, chain);
+    renderPasteStatus("");
+  } catch (err) {
+    console.error("Failed to paste transformed:", err);
+    renderPasteStatus(err);
+  }
+}
+
+// Leaves the picker; refresh brings back the history (or search results).
+function closeTransforms(refresh) {
+  transforming = false;
+  transformTarget = null;
+  if (!refresh) return;
+  if (query) {
+    runSearch();
+  } else {
+    refreshHistory();
+  }
+}
+
+function handleTransformKey(e) {
+  switch (e.key) {
+    case "Escape":
+    case "Tab":
+      closeTransforms(true);
+      break;
+    case "ArrowDown":
+      transformIndex = (transformIndex + 1) % transforms.length;
+      renderTransforms();
+      break;
+    case "ArrowUp":
+      transformIndex = (transformIndex - 1 + transforms.length) % transforms.length;
+      renderTransforms();
+      break;
+    case " ":
+      toggleTransform();
+      break;
+    case "Enter":
+      applyTransforms();
+      break;
+    default:
+      if (e.key.length === 1) e.preventDefault(); // Keep typing out of the search box
+      return;
+  }
+  e.preventDefault();
+}
+
+// ── Hotkey registration failures ─────────────────────────────────────────────
+const hotkeyLabels = {
+  show: "Show",
+  pastePrevious: "Paste previous",
+  togglePause: "Pause capture",
+};
+
+// Problems shown in the notice area, by source.
+const notices = { hotkeys: "", storage: "", paste: "" };
+
+function renderNotices() {
+  const text = Object.values(notices).filter(Boolean).join("\n");
+  islandNotice.textContent = text;
+  islandNotice.classList.toggle("hidden", text === "");
+}
+
+function renderHotkeyStatus(statuses) {
+  const failed = (statuses || []).filter((status) => status.error);
+  notices.hotkeys = failed
+    .map((status) => `${hotkeyLabels[status.action] || status.action} hotkey ${status.hotkey} unavailable: ${status.error}`)
+    .join("\n");
+  renderNotices();
+}
+
+Events.On("hotkeys:changed", (event) => {
+  renderHotkeyStatus(event.data);
+});
+
+App.GetHotkeyStatus()
+  .then(renderHotkeyStatus)
+  .catch((err) => console.error("Failed to get hotkey status:", err));
+
+// ── Paste failures; the window stays up so the user sees why ────────────────
+function renderPasteStatus(err) {
+  notices.paste = err ? `Paste failed: ${err.message || err}` : "";
+  renderNotices();
+  if (err && !isOpen) {
+    island.classList.add("open");
+    isOpen = true;
+  }
+}
+
+// ── History storage errors (e.g. a history that cannot be decrypted) ─────────
+function renderStorageStatus(status) {
+  notices.storage = status && status.error ? `History storage: ${status.error}` : "";
+  renderNotices();
+}
+
+Events.On("storage:changed", (event) => {
+  renderStorageStatus(event.data);
+});
+
+App.GetStorageStatus()
+  .then(renderStorageStatus)
+  .catch((err) => console.error("Failed to get storage status:", err));
+
+// ── Capture pause state; clicking the pill resumes ───────────────────────────
+function renderPauseStatus(status) {
+  const paused = !!(status && status.paused);
+  islandPaused.classList.toggle("hidden", !paused);
+  if (!paused) return;
+  if (status.until) {
+    const until = new Date(status.until).toLocaleTimeString([], { hour: "numeric", minute: "2-digit" });
+    islandPaused.textContent = `Paused until ${until}`;
+  } else {
+    islandPaused.textContent = "Paused";
+  }
+}
+
+islandPaused.addEventListener("click", () => {
+  App.ResumeCapture().catch((err) => console.error("Failed to resume capture:", err));
+});
+
+Events.On("pause:changed", (event) => {
+  renderPauseStatus(event.data);
+});
+
+App.GetPauseStatus()
+  .then(renderPauseStatus)
+  .catch((err) => console.error("Failed to get pause status:", err));
+
 // ── Hotkey event from Go ──────────────────────────────────────────────────────
 Events.On("hotkey", () => {
   island.classList.remove("open");
@@ -169,10 +639,17 @@ Events.On("hotkey", () => {
   island.classList.add("open");
   isOpen = true;
   selectedIndex = 0;
+  closeSearch(false);
+  closeTransforms(false);
   window.focus();
   refreshHistory();
 });
 
+// ── History changes pushed from Go ───────────────────────────────────────────
+Events.On("history:changed", (event) => {
+  applyChange(event.data);
+});
+
 // ── Dismiss helper ────────────────────────────────────────────────────────────
 function dismiss() {
   if (!isOpen) return;
@@ -189,9 +666,31 @@ function dismiss() {
 document.addEventListener("keydown", (e) => {
   if (!isOpen) return;
 
+  if (transforming) {
+    handleTransformKey(e);
+    return;
+  }
+
+  if (e.key === "Tab") {
+    e.preventDefault();
+    openTransforms();
+    return;
+  }
+
   if (e.key === "Escape") {
     e.preventDefault();
-    dismiss();
+    if (query) {
+      closeSearch(true);
+    } else {
+      dismiss();
+    }
+    return;
+  }
+
+  // Typing a character starts filtering
+  if (e.key.length === 1 && !e.metaKey && !e.ctrlKey && !e.altKey && document.activeElement !== islandSearch) {
+    e.preventDefault();
+    openSearch(e.key);
     return;
   }
 
@@ -222,7 +721,7 @@ document.addEventListener("keydown", (e) => {
   if (e.key === "Enter") {
     e.preventDefault();
     if (selectedIndex >= 0 && selectedIndex < allItems.length) {
-      selectAndPaste(selectedIndex);
+      selectAndPaste(selectedIndex, pasteModeFor(e));
     }
     return;
   }
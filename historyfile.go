@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// historyVersion is the history file format saveHistory writes. Version 1
+// was a bare JSON array of items; version 2 wraps it in historyFile.
+const historyVersion = 2
+
+// historyBackups is how many previous history files are kept as
+// history.json.1 (newest) to history.json.<historyBackups>.
+const historyBackups = 3
+
+// historyBackupInterval is how often the backups rotate: on the first save
+// of a session and then at most this often, so a burst of debounced saves
+// does not push every older backup out within seconds.
+const historyBackupInterval = time.Hour
+
+// historyFile is the envelope of history.json, so the format can grow
+// without breaking older readers in silent ways.
+type historyFile struct {
+	Version int        `json:"version"`
+	Items   []ClipItem `json:"items"`
+}
+
+// historyMigrations upgrade a history document one version at a time:
+// historyMigrations[v-1] turns version v into version v+1.
+var historyMigrations = []func(json.RawMessage) (json.RawMessage, error){
+	migrateHistoryV1,
+}
+
+// Errors that mean the history on disk exists but must not be replaced.
+var (
+	errCannotDecrypt = errors.New("cannot decrypt history")
+	errNewerHistory  = errors.New("history was written by a newer version")
+)
+
+// migrateHistoryV1 wraps the bare item array of version 1 in an envelope.
+func migrateHistoryV1(doc json.RawMessage) (json.RawMessage, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(doc, &items); err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Version int               `json:"version"`
+		Items   []json.RawMessage `json:"items"`
+	}{2, items})
+}
+
+// encodeHistory returns the current file format for items.
+func encodeHistory(items []ClipItem) ([]byte, error) {
+	return json.MarshalIndent(historyFile{Version: historyVersion, Items: items}, "", "  ")
+}
+
+// decodeHistory parses a history document of any known version,
+// migrating it to the current one.
+func decodeHistory(data []byte) ([]ClipItem, error) {
+	doc := json.RawMessage(data)
+	version, err := historyDocVersion(doc)
+	if err != nil {
+		return nil, err
+	}
+	if version > historyVersion {
+		return nil, fmt.Errorf("%w (format %d, this app reads up to %d)", errNewerHistory, version, historyVersion)
+	}
+	for ; version < historyVersion; version++ {
+		if doc, err = historyMigrations[version-1](doc); err != nil {
+			return nil, fmt.Errorf("migrate from format %d: %w", version, err)
+		}
+	}
+	var file historyFile
+	if err := json.Unmarshal(doc, &file); err != nil {
+		return nil, err
+	}
+	return file.Items, nil
+}
+
+// historyDocVersion returns the format version of a history document.
+func historyDocVersion(doc json.RawMessage) (int, error) {
+	if trimmed := bytes.TrimLeft(doc, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		return 1, nil
+	}
+	var head struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(doc, &head); err != nil {
+		return 0, err
+	}
+	if head.Version < 1 {
+		return 0, errors.New("history file has no version")
+	}
+	return head.Version, nil
+}
+
+// backupPath returns the path of the n-th most recent backup of path.
+func backupPath(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
+
+// rotateBackups shifts the backups of path down by one and makes the
+// current file the newest backup, leaving the current file in place. It
+// reports whether it did, which it does not when there is no current file.
+func rotateBackups(path string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	for n := historyBackups - 1; n >= 1; n-- {
+		if err := os.Rename(backupPath(path, n), backupPath(path, n+1)); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+	newest := backupPath(path, 1)
+	if err := os.Remove(newest); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if err := os.Link(path, newest); err != nil {
+		return true, copyFile(path, newest) // Filesystems without hard links
+	}
+	// Files from older versions may be world-readable; the link shares the mode
+	return true, os.Chmod(newest, 0600)
+}
+
+// copyFile atomically copies src to dst, readable by the owner only.
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(dst, data, 0600)
+}
+
+// writeFileAtomic writes data to a temporary file next to path, syncs it
+// and renames it over path, so readers and crashes see either the old or
+// the new content, never a partial write.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes a directory entry change, such as a rename, to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
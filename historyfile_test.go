@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestDecodeHistory_Versions verifies the unversioned array migrates, the
+// current envelope decodes, and newer or broken files are rejected.
+func TestDecodeHistory_Versions(t *testing.T) {
+	items, err := decodeHistory([]byte(`[{"id": "a", "type": "text", "text": "old"}]`))
+	if err != nil || len(items) != 1 || items[0].Text != "old" {
+		t.Fatalf("version 1: got %+v, %v", items, err)
+	}
+
+	data, err := encodeHistory([]ClipItem{{ID: "b", Type: TypeText, Text: "new"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err = decodeHistory(data)
+	if err != nil || len(items) != 1 || items[0].Text != "new" {
+		t.Fatalf("current version: got %+v, %v", items, err)
+	}
+
+	if _, err := decodeHistory([]byte(`{"version": 99, "items": []}`)); !errors.Is(err, errNewerHistory) {
+		t.Errorf("expected errNewerHistory, got %v", err)
+	}
+	for _, bad := range []string{`[{"id": `, `{"items": []}`, ``} {
+		if _, err := decodeHistory([]byte(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+// TestWriteFileAtomic verifies the content and mode land at the path and no
+// temporary file is left behind.
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.json")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("got %q", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("got mode %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the file, got %d entries", len(entries))
+	}
+}
+
+// TestPersist_BackupsRotate verifies saves historyBackupInterval apart
+// keep the previous file as a backup, up to historyBackups of them, and
+// saves in between leave the backups alone.
+func TestPersist_BackupsRotate(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, true)
+	clk := newFakeClock()
+	app.clock = clk
+	for _, text := range []string{"one", "two", "three", "four", "five"} {
+		clk.Advance(historyBackupInterval)
+		app.addItem(text)
+		app.flushSave()
+	}
+	clk.Advance(time.Minute)
+	app.addItem("six")
+	app.flushSave()
+
+	path := app.historyPath()
+	for n := 1; n <= historyBackups; n++ {
+		data, err := os.ReadFile(backupPath(path, n))
+		if err != nil {
+			t.Fatalf("backup %d: %v", n, err)
+		}
+		items, err := decodeHistory(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 5 - n; len(items) != want {
+			t.Errorf("backup %d: expected %d items, got %d", n, want, len(items))
+		}
+	}
+	if _, err := os.Stat(backupPath(path, historyBackups+1)); !os.IsNotExist(err) {
+		t.Errorf("expected at most %d backups, got %v", historyBackups, err)
+	}
+}
+
+// TestPersist_FallsBackToBackup verifies a corrupt history file is set
+// aside and the newest good backup is loaded and written back.
+func TestPersist_FallsBackToBackup(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, true)
+	app.addItem("first")
+	app.flushSave()
+	app.addItem("second")
+	app.flushSave()
+	if err := os.WriteFile(app.historyPath(), []byte(`{"version": 2, "items": [{"te`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newPersistentApp(t, dir, true)
+	restored.loadHistory()
+	if len(restored.history) != 1 || restored.history[0].Text != "first" {
+		t.Fatalf("expected the backup's single item, got %+v", restored.history)
+	}
+	if _, err := os.Stat(restored.historyPath() + ".corrupt"); err != nil {
+		t.Errorf("expected the corrupt file to be set aside: %v", err)
+	}
+
+	restored.flushSave()
+	data, err := os.ReadFile(restored.historyPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items, err := decodeHistory(data); err != nil || len(items) != 1 {
+		t.Errorf("expected the restored history to be saved, got %d items, %v", len(items), err)
+	}
+}
+
+// TestPersist_FallbackKeepsBackupImages verifies an image only a backup
+// still points at survives saves and loads, so falling back to that backup
+// restores it.
+func TestPersist_FallbackKeepsBackupImages(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, false)
+	app.addImageItem(testImage(1))
+	id := app.history[0].ID
+	app.TogglePin(id)
+	app.flushSave()
+	app.TogglePin(id)
+	app.flushSave()
+
+	// A restart in between sweeps images that no reference accounts for
+	newPersistentApp(t, dir, false).loadHistory()
+	if err := os.WriteFile(app.historyPath(), []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newPersistentApp(t, dir, false)
+	restored.loadHistory()
+	if len(restored.history) != 1 || restored.history[0].Type != TypeImage || restored.history[0].ImageData == "" {
+		t.Fatalf("expected the backup's pinned image, got %+v", restored.history)
+	}
+}
+
+// TestPersist_NewerFormatBlocksSaving verifies a file from a newer version
+// is reported and not overwritten.
+func TestPersist_NewerFormatBlocksSaving(t *testing.T) {
+	dir := t.TempDir()
+	future := []byte(`{"version": 99, "items": [], "somethingNew": true}`)
+	path := filepath.Join(dir, "history.json")
+	if err := os.WriteFile(path, future, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	app := newPersistentApp(t, dir, true)
+	app.loadHistory()
+	if app.GetStorageStatus().Error == "" {
+		t.Error("expected a storage error")
+	}
+	app.addItem("clip")
+	app.flushSave()
+	if data, _ := os.ReadFile(path); string(data) != string(future) {
+		t.Error("newer history file was overwritten")
+	}
+}
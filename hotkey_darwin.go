@@ -0,0 +1,19 @@
+package main
+
+import "golang.design/x/hotkey"
+
+// modifierNames maps the modifier part of a hotkey spec to this platform's modifier.
+var modifierNames = map[string]hotkey.Modifier{
+	"ctrl":    hotkey.ModCtrl,
+	"control": hotkey.ModCtrl,
+	"shift":   hotkey.ModShift,
+	"alt":     hotkey.ModOption,
+	"opt":     hotkey.ModOption,
+	"option":  hotkey.ModOption,
+	"cmd":     hotkey.ModCmd,
+	"command": hotkey.ModCmd,
+	"super":   hotkey.ModCmd,
+}
+
+// defaultHotkey shows the island when settings do not name a hotkey.
+const defaultHotkey = "cmd+shift+v"
@@ -0,0 +1,17 @@
+package main
+
+import "golang.design/x/hotkey"
+
+// modifierNames maps the modifier part of a hotkey spec to this platform's modifier.
+var modifierNames = map[string]hotkey.Modifier{
+	"ctrl":    hotkey.ModCtrl,
+	"control": hotkey.ModCtrl,
+	"shift":   hotkey.ModShift,
+	"alt":     hotkey.Mod1,
+	"super":   hotkey.Mod4,
+	"win":     hotkey.Mod4,
+	"meta":    hotkey.Mod4,
+}
+
+// defaultHotkey shows the island when settings do not name a hotkey.
+const defaultHotkey = "ctrl+shift+v"
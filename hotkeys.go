@@ -0,0 +1,251 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"golang.design/x/hotkey"
+)
+
+// keyNames maps the key part of a hotkey spec to its key code.
+var keyNames = func() map[string]hotkey.Key {
+	keys := map[string]hotkey.Key{
+		"space": hotkey.KeySpace, "return": hotkey.KeyReturn, "enter": hotkey.KeyReturn,
+		"escape": hotkey.KeyEscape, "esc": hotkey.KeyEscape, "delete": hotkey.KeyDelete,
+		"tab": hotkey.KeyTab, "left": hotkey.KeyLeft, "right": hotkey.KeyRight,
+		"up": hotkey.KeyUp, "down": hotkey.KeyDown,
+	}
+	letters := []hotkey.Key{
+		hotkey.KeyA, hotkey.KeyB, hotkey.KeyC, hotkey.KeyD, hotkey.KeyE, hotkey.KeyF,
+		hotkey.KeyG, hotkey.KeyH, hotkey.KeyI, hotkey.KeyJ, hotkey.KeyK, hotkey.KeyL,
+		hotkey.KeyM, hotkey.KeyN, hotkey.KeyO, hotkey.KeyP, hotkey.KeyQ, hotkey.KeyR,
+		hotkey.KeyS, hotkey.KeyT, hotkey.KeyU, hotkey.KeyV, hotkey.KeyW, hotkey.KeyX,
+		hotkey.KeyY, hotkey.KeyZ,
+	}
+	for i, k := range letters {
+		keys[string(rune('a'+i))] = k
+	}
+	digits := []hotkey.Key{
+		hotkey.Key0, hotkey.Key1, hotkey.Key2, hotkey.Key3, hotkey.Key4,
+		hotkey.Key5, hotkey.Key6, hotkey.Key7, hotkey.Key8, hotkey.Key9,
+	}
+	for i, k := range digits {
+		keys[string(rune('0'+i))] = k
+	}
+	functions := []hotkey.Key{
+		hotkey.KeyF1, hotkey.KeyF2, hotkey.KeyF3, hotkey.KeyF4, hotkey.KeyF5,
+		hotkey.KeyF6, hotkey.KeyF7, hotkey.KeyF8, hotkey.KeyF9, hotkey.KeyF10,
+		hotkey.KeyF11, hotkey.KeyF12, hotkey.KeyF13, hotkey.KeyF14, hotkey.KeyF15,
+		hotkey.KeyF16, hotkey.KeyF17, hotkey.KeyF18, hotkey.KeyF19, hotkey.KeyF20,
+	}
+	for i, k := range functions {
+		keys[fmt.Sprintf("f%d", i+1)] = k
+	}
+	return keys
+}()
+
+// parseHotkey parses a spec like "ctrl+shift+v" (case-insensitive) into
+// modifiers and a key. Modifier names are platform-specific, see modifierNames.
+func parseHotkey(spec string) ([]hotkey.Modifier, hotkey.Key, error) {
+	parts := strings.Split(strings.ToLower(strings.ReplaceAll(spec, " ", "")), "+")
+	if len(parts) < 2 {
+		return nil, 0, fmt.Errorf("hotkey %q: need at least one modifier and a key", spec)
+	}
+
+	var mods []hotkey.Modifier
+	seen := make(map[hotkey.Modifier]bool)
+	for _, name := range parts[:len(parts)-1] {
+		mod, ok := modifierNames[name]
+		if !ok {
+			return nil, 0, fmt.Errorf("hotkey %q: unknown modifier %q", spec, name)
+		}
+		if !seen[mod] {
+			seen[mod] = true
+			mods = append(mods, mod)
+		}
+	}
+
+	key, ok := keyNames[parts[len(parts)-1]]
+	if !ok {
+		return nil, 0, fmt.Errorf("hotkey %q: unknown key %q", spec, parts[len(parts)-1])
+	}
+	return mods, key, nil
+}
+
+// hotkeyBinding is a registered global hotkey and the goroutine serving it.
+type hotkeyBinding struct {
+	spec string
+	hk   *hotkey.Hotkey
+}
+
+// registerHotkey grabs spec system-wide and calls action on every keydown
+// until the binding is unregistered.
+func registerHotkey(spec string, action func()) (*hotkeyBinding, error) {
+	mods, key, err := parseHotkey(spec)
+	if err != nil {
+		return nil, err
+	}
+	hk := hotkey.New(mods, key)
+	if err := hk.Register(); err != nil {
+		return nil, fmt.Errorf("register %s: %w", spec, err)
+	}
+	keydown := hk.Keydown() // Closed by Unregister, which ends the goroutine
+	go func() {
+		for range keydown {
+			action()
+		}
+	}()
+	return &hotkeyBinding{spec: spec, hk: hk}, nil
+}
+
+func (b *hotkeyBinding) unregister() {
+	if err := b.hk.Unregister(); err != nil {
+		log.Printf("[clipboard] failed to unregister hotkey %s: %v", b.spec, err)
+	}
+}
+
+// Hotkey actions, as reported in HotkeyStatus.Action.
+const (
+	HotkeyShow          = "show"
+	HotkeyPastePrevious = "pastePrevious"
+	HotkeyTogglePause   = "togglePause"
+)
+
+// EventHotkeysChanged is emitted with []HotkeyStatus whenever hotkeys are
+// (re-)registered, so the UI can report ones that failed.
+const EventHotkeysChanged = "hotkeys:changed"
+
+// HotkeyStatus reports one configured global hotkey. Error explains why it
+// could not be registered, e.g. because another app already holds it.
+type HotkeyStatus struct {
+	Action string `json:"action"`
+	Hotkey string `json:"hotkey"`
+	Active bool   `json:"active"`
+	Error  string `json:"error,omitempty"`
+}
+
+// hotkeyAction ties a hotkey setting to what it does.
+type hotkeyAction struct {
+	name string
+	spec func(Settings) string
+	run  func()
+}
+
+func (a *App) hotkeyActions() []hotkeyAction {
+	return []hotkeyAction{
+		{HotkeyShow, func(s Settings) string { return s.Hotkey }, a.showIsland},
+		{HotkeyPastePrevious, func(s Settings) string { return s.PastePreviousHotkey }, a.pastePrevious},
+		{HotkeyTogglePause, func(s Settings) string { return s.TogglePauseHotkey }, a.togglePause},
+	}
+}
+
+// startHotkeys registers the hotkeys from settings. Until it has run,
+// settings changes do not touch global hotkeys (so tests never do).
+func (a *App) startHotkeys() error {
+	a.hotkeyMu.Lock()
+	a.hotkeysActive = true
+	a.hotkeyMu.Unlock()
+	return a.applyHotkeys(a.GetSettings())
+}
+
+// stopHotkeys unregisters every hotkey. Settings changes no longer touch
+// global hotkeys afterwards.
+func (a *App) stopHotkeys() {
+	a.hotkeyMu.Lock()
+	defer a.hotkeyMu.Unlock()
+	a.hotkeysActive = false
+	for name, binding := range a.hotkeys {
+		binding.unregister()
+		delete(a.hotkeys, name)
+	}
+}
+
+// applyHotkeys moves every hotkey to its spec in settings, leaving unchanged
+// ones alone. A hotkey that cannot be registered keeps its previous binding
+// if there was one; failures are returned together and published in the
+// hotkey status.
+func (a *App) applyHotkeys(settings Settings) error {
+	a.hotkeyMu.Lock()
+	if !a.hotkeysActive {
+		a.hotkeyMu.Unlock()
+		return nil
+	}
+	if a.hotkeys == nil {
+		a.hotkeys = make(map[string]*hotkeyBinding)
+	}
+	actions := a.hotkeyActions()
+
+	// Release changed hotkeys first, so two actions can swap keys.
+	prev := make(map[string]*hotkeyBinding)
+	for _, action := range actions {
+		binding := a.hotkeys[action.name]
+		if binding != nil && binding.spec != action.spec(settings) {
+			binding.unregister()
+			delete(a.hotkeys, action.name)
+			prev[action.name] = binding
+		}
+	}
+
+	var statuses []HotkeyStatus
+	var errs []error
+	for _, action := range actions {
+		spec := action.spec(settings)
+		if spec == "" {
+			continue
+		}
+		status := HotkeyStatus{Action: action.name, Hotkey: spec, Active: true}
+		if a.hotkeys[action.name] == nil {
+			binding, err := registerHotkey(spec, action.run)
+			if err != nil {
+				if old := prev[action.name]; old != nil {
+					if restored, rerr := registerHotkey(old.spec, action.run); rerr == nil {
+						a.hotkeys[action.name] = restored
+					}
+				}
+				log.Printf("[clipboard] hotkey %s for %s failed: %v", spec, action.name, err)
+				status.Active = false
+				status.Error = err.Error()
+				errs = append(errs, err)
+			} else {
+				a.hotkeys[action.name] = binding
+				log.Printf("[clipboard] Hotkey %s active for %s", spec, action.name)
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	a.hotkeyStatus = statuses
+
+	if show := a.hotkeys[HotkeyShow]; show != nil && a.showMenuItem != nil {
+		a.showMenuItem.SetLabel(showMenuLabel(show.spec))
+	}
+	a.hotkeyMu.Unlock()
+
+	if a.emit != nil {
+		a.emit(EventHotkeysChanged, statuses)
+	}
+	return errors.Join(errs...)
+}
+
+// GetHotkeyStatus reports the configured hotkeys and whether each one is
+// registered. Exported for Wails binding.
+func (a *App) GetHotkeyStatus() []HotkeyStatus {
+	a.hotkeyMu.Lock()
+	defer a.hotkeyMu.Unlock()
+	return slices.Clone(a.hotkeyStatus)
+}
+
+// showMenuLabel is the tray menu label for the show-island item.
+func showMenuLabel(spec string) string {
+	return "Show Clipboard  (" + spec + ")"
+}
+
+// hotkeyKey returns a canonical form of a parsed hotkey, equal for specs
+// that name the same key combination.
+func hotkeyKey(mods []hotkey.Modifier, key hotkey.Key) string {
+	mods = slices.Clone(mods)
+	slices.Sort(mods)
+	return fmt.Sprint(mods, key)
+}
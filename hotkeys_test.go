@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+// TestParseHotkey verifies hotkey specs are parsed case-insensitively and
+// malformed specs are rejected.
+func TestParseHotkey(t *testing.T) {
+	valid := []string{defaultHotkey, "ctrl+shift+v", "Ctrl + Shift + F12", "shift+space", "ctrl+ctrl+1"}
+	for _, spec := range valid {
+		mods, _, err := parseHotkey(spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", spec, err)
+			continue
+		}
+		if len(mods) == 0 {
+			t.Errorf("%q: expected modifiers", spec)
+		}
+	}
+
+	invalid := []string{"", "v", "ctrl+", "hyper+v", "ctrl+shift+nosuchkey"}
+	for _, spec := range invalid {
+		if _, _, err := parseHotkey(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+// TestParseHotkey_DedupesModifiers verifies repeated modifiers are merged.
+func TestParseHotkey_DedupesModifiers(t *testing.T) {
+	mods, _, err := parseHotkey("ctrl+control+v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 1 {
+		t.Errorf("expected 1 modifier, got %d", len(mods))
+	}
+}
+
+// TestValidateHotkeys verifies optional hotkeys may be empty but must not
+// collide with each other.
+func TestValidateHotkeys(t *testing.T) {
+	if err := validateHotkeys("ctrl+shift+v", "", ""); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateHotkeys("ctrl+shift+v", "ctrl+shift+p", "ctrl+shift+1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateHotkeys("ctrl+shift+v", "Shift+Ctrl+V"); err == nil {
+		t.Error("expected conflict for the same combination")
+	}
+	if err := validateHotkeys("ctrl+shift+v", "ctrl+nosuchkey"); err == nil {
+		t.Error("expected error for invalid optional hotkey")
+	}
+}
+
+// TestApplyHotkeys_Inactive verifies settings changes leave global hotkeys
+// alone until startHotkeys has run.
+func TestApplyHotkeys_Inactive(t *testing.T) {
+	app := &App{}
+	settings := app.GetSettings()
+	settings.PastePreviousHotkey = "ctrl+shift+p"
+	if err := app.UpdateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if status := app.GetHotkeyStatus(); len(status) != 0 {
+		t.Errorf("expected no hotkey status, got %+v", status)
+	}
+}
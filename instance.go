@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+// The running instance listens on a Unix socket in a private directory in
+// the XDG runtime dir. Holding it is the single-instance lock; later launches connect to it and
+// forward their arguments as one JSON instanceRequest line, answered by one
+// instanceResponse line.
+
+// errInstanceRunning is returned by listenInstance when another instance
+// holds the socket.
+var errInstanceRunning = errors.New("another instance is running")
+
+// instanceTimeout bounds connecting to the socket and each request on it.
+const instanceTimeout = 5 * time.Second
+
+// maxInstanceMessage bounds one request or response line.
+const maxInstanceMessage = 64 << 20
+
+// instanceRequest is a forwarded command line, e.g. ["paste", "3"].
+type instanceRequest struct {
+	Args []string `json:"args"`
+}
+
+// instanceResponse carries a command's result, or why it failed.
+type instanceResponse struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// instanceCommand is something a later launch can ask the running instance
+// to do. run gets the arguments after the command name.
+type instanceCommand struct {
+	usage string
+	run   func(args []string) (any, error)
+}
+
+// instanceSocketPath returns where the running instance listens. The XDG
+// runtime dir may fall back to a shared temporary directory, hence the
+// private subdirectory.
+func instanceSocketPath() string {
+	return filepath.Join(xdg.RuntimeDir, "clipboard-island", "instance.sock")
+}
+
+// listenInstance takes the single-instance lock by listening on path, in a
+// directory only this user can enter. It returns errInstanceRunning if
+// another instance answers there, and replaces the socket a crashed
+// instance left behind otherwise. Launches at the same time take turns
+// through a lock file, so neither removes the socket the other just made.
+func listenInstance(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := checkPrivateDir(dir); err != nil {
+		return nil, err
+	}
+	unlock, err := lockInstance(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		conn, dialErr := net.DialTimeout("unix", path, instanceTimeout)
+		if dialErr == nil {
+			conn.Close()
+			return nil, errInstanceRunning
+		}
+		if rmErr := os.Remove(path); rmErr != nil && !os.IsNotExist(rmErr) {
+			return nil, err
+		}
+		log.Printf("[clipboard] Replacing stale instance socket %s", path)
+		if listener, err = net.Listen("unix", path); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// serveInstance answers forwarded commands on listener until it is closed
+// by releaseInstance.
+func (a *App) serveInstance(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("[clipboard] instance socket: %v", err)
+			}
+			return
+		}
+		go a.handleInstanceConn(conn)
+	}
+}
+
+// handleInstanceConn answers the one request a connection carries.
+func (a *App) handleInstanceConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(instanceTimeout))
+
+	var resp instanceResponse
+	var req instanceRequest
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, maxInstanceMessage)
+	if !scanner.Scan() {
+		return
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+		resp.Error = "malformed request: " + err.Error()
+	} else if result, err := a.runInstanceCommand(req.Args); err != nil {
+		resp.Error = err.Error()
+	} else if result != nil {
+		if resp.Result, err = json.Marshal(result); err != nil {
+			resp.Error = err.Error()
+		}
+	}
+	data, _ := json.Marshal(resp) // RawMessage and strings always marshal
+	conn.Write(append(data, '\n'))
+}
+
+// runInstanceCommand runs a forwarded command line. No arguments, as from
+// launching the app again, shows the island.
+func (a *App) runInstanceCommand(args []string) (any, error) {
+	if a.stopping.Load() {
+		return nil, errors.New("shutting down")
+	}
+	if len(args) == 0 {
+		args = []string{"show"}
+	}
+	commands := a.instanceCommands()
+	command, ok := commands[args[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown command %q (try %s)", args[0], commandNames(commands))
+	}
+	log.Printf("[clipboard] Forwarded command: %s", strings.Join(args, " "))
+	return command.run(args[1:])
+}
+
+// instanceCommands lists the commands a later launch can forward, see
+// cliUsage. They call the same App methods the frontend binds to.
+func (a *App) instanceCommands() map[string]instanceCommand {
+	return map[string]instanceCommand{
+		"show": {"show", func(args []string) (any, error) {
+			if err := cliArgs(args, 0, 0, "show"); err != nil {
+				return nil, err
+			}
+			a.showIsland()
+			return nil, nil
+		}},
+		"paste": {"paste <item>", func(args []string) (any, error) {
+			if err := cliArgs(args, 1, 1, "paste <item>"); err != nil {
+				return nil, err
+			}
+			item, err := a.resolveItem(args[0])
+			if err != nil {
+				return nil, err
+			}
+			a.capturePreviousApp()
+			return nil, a.pasteItem(item.ID, a.GetSettings().PasteMode, nil)
+		}},
+		"list": {"list [n]", func(args []string) (any, error) {
+			if err := cliArgs(args, 0, 1, "list [n]"); err != nil {
+				return nil, err
+			}
+			limit := 0
+			if len(args) == 1 {
+				var err error
+				if limit, err = strconv.Atoi(args[0]); err != nil || limit < 1 {
+					return nil, fmt.Errorf("n must be a positive number, got %q", args[0])
+				}
+			}
+			return a.cliList(limit), nil
+		}},
+		"get": {"get <item>", func(args []string) (any, error) {
+			if err := cliArgs(args, 1, 1, "get <item>"); err != nil {
+				return nil, err
+			}
+			return a.resolveItem(args[0])
+		}},
+		"search": {"search <query>", func(args []string) (any, error) {
+			if err := cliArgs(args, 1, math.MaxInt, "search <query>"); err != nil {
+				return nil, err
+			}
+			return a.cliSearch(strings.Join(args, " ")), nil
+		}},
+		"copy": {"copy [text]", func(args []string) (any, error) {
+			if err := cliArgs(args, 1, math.MaxInt, "copy [text]"); err != nil {
+				return nil, err
+			}
+			return nil, a.copyText(strings.Join(args, " "))
+		}},
+		"pin": {"pin <item>", func(args []string) (any, error) {
+			if err := cliArgs(args, 1, 1, "pin <item>"); err != nil {
+				return nil, err
+			}
+			return a.cliSetPinned(args[0], true)
+		}},
+		"unpin": {"unpin <item>", func(args []string) (any, error) {
+			if err := cliArgs(args, 1, 1, "unpin <item>"); err != nil {
+				return nil, err
+			}
+			return a.cliSetPinned(args[0], false)
+		}},
+		"delete": {"delete <item>", func(args []string) (any, error) {
+			if err := cliArgs(args, 1, 1, "delete <item>"); err != nil {
+				return nil, err
+			}
+			item, err := a.resolveItem(args[0])
+			if err != nil {
+				return nil, err
+			}
+			a.DeleteItem(item.ID)
+			return nil, nil
+		}},
+		"clear": {"clear", func(args []string) (any, error) {
+			if err := cliArgs(args, 0, 0, "clear"); err != nil {
+				return nil, err
+			}
+			return a.ClearHistory(), nil
+		}},
+		"pause": {"pause [minutes]", func(args []string) (any, error) {
+			if err := cliArgs(args, 0, 1, "pause [minutes]"); err != nil {
+				return nil, err
+			}
+			minutes := 0
+			if len(args) == 1 {
+				var err error
+				if minutes, err = strconv.Atoi(args[0]); err != nil {
+					return nil, fmt.Errorf("minutes must be a number, got %q", args[0])
+				}
+			}
+			if err := a.PauseCapture(minutes); err != nil {
+				return nil, err
+			}
+			return a.GetPauseStatus(), nil
+		}},
+		"resume": {"resume", func(args []string) (any, error) {
+			if err := cliArgs(args, 0, 0, "resume"); err != nil {
+				return nil, err
+			}
+			a.ResumeCapture()
+			return a.GetPauseStatus(), nil
+		}},
+	}
+}
+
+// commandNames lists the command usages, sorted, for error messages.
+func commandNames(commands map[string]instanceCommand) string {
+	var usages []string
+	for _, command := range commands {
+		usages = append(usages, command.usage)
+	}
+	slices.Sort(usages)
+	return strings.Join(usages, ", ")
+}
+
+// releaseInstance closes the instance socket, removing it, so the next
+// launch becomes the running instance.
+func (a *App) releaseInstance() {
+	if a.instance == nil {
+		return
+	}
+	if err := a.instance.Close(); err != nil {
+		log.Printf("[clipboard] failed to release instance socket: %v", err)
+	}
+}
+
+// forwardCommand sends args to the instance listening on path and returns
+// its result.
+func forwardCommand(path string, args []string) (json.RawMessage, error) {
+	conn, err := net.DialTimeout("unix", path, instanceTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("clipboard-island is not running: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(instanceTimeout))
+
+	data, err := json.Marshal(instanceRequest{Args: args})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, maxInstanceMessage)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("no response from the running instance")
+	}
+	var resp instanceResponse
+	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return resp.Result, nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSocketPath returns a socket path in a fresh directory. It avoids
+// t.TempDir, whose long names can exceed the Unix socket path limit.
+func testSocketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "island")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "test.sock")
+}
+
+// serveTestInstance makes app the running instance on a fresh socket.
+func serveTestInstance(t *testing.T, app *App) string {
+	t.Helper()
+	path := testSocketPath(t)
+	listener, err := listenInstance(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.instance = listener
+	go app.serveInstance(listener)
+	t.Cleanup(app.releaseInstance)
+	return path
+}
+
+// TestInstance_ForwardsCommands verifies a second launch finds the running
+// instance and that forwarded commands run there.
+func TestInstance_ForwardsCommands(t *testing.T) {
+	clip := newFakeBackend()
+	app := NewApp(clip)
+	app.addItem("older")
+	app.addItem("newer")
+	path := serveTestInstance(t, app)
+
+	if _, err := listenInstance(path); !errors.Is(err, errInstanceRunning) {
+		t.Fatalf("expected errInstanceRunning, got %v", err)
+	}
+
+	if _, err := forwardCommand(path, []string{"paste", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-clip.pasted:
+		if string(got) != "older" {
+			t.Errorf("expected 'older' pasted, got %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("paste was never simulated")
+	}
+
+	if _, err := forwardCommand(path, nil); err != nil {
+		t.Errorf("plain relaunch: %v", err)
+	}
+	for _, args := range [][]string{{"paste", "3"}, {"paste", "x"}, {"paste"}, {"frobnicate"}} {
+		if _, err := forwardCommand(path, args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+// TestInstance_ReleasedOnShutdown verifies shutdown removes the socket so
+// the next launch becomes the running instance, and refuses commands once
+// it has started.
+func TestInstance_ReleasedOnShutdown(t *testing.T) {
+	app := NewApp(newFakeBackend())
+	path := serveTestInstance(t, app)
+	app.shutdown()
+
+	if _, err := forwardCommand(path, []string{"show"}); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("expected not running after shutdown, got %v", err)
+	}
+	listener, err := listenInstance(path)
+	if err != nil {
+		t.Fatalf("next launch could not take over: %v", err)
+	}
+	listener.Close()
+
+	if _, err := app.runInstanceCommand([]string{"show"}); err == nil {
+		t.Error("expected commands refused after shutdown")
+	}
+}
+
+// TestInstance_ReplacesStaleSocket verifies a socket left by a crashed
+// instance does not block the next launch.
+func TestInstance_ReplacesStaleSocket(t *testing.T) {
+	path := testSocketPath(t)
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the stale socket to remain: %v", err)
+	}
+
+	listener, err := listenInstance(path)
+	if err != nil {
+		t.Fatalf("expected the stale socket to be replaced, got %v", err)
+	}
+	listener.Close()
+}
+
+// TestInstance_ConcurrentLaunches verifies launches take turns replacing a
+// stale socket and end up with exactly one running instance, still
+// reachable.
+func TestInstance_ConcurrentLaunches(t *testing.T) {
+	path := testSocketPath(t)
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	unlock, err := lockInstance(path + ".lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waiting := make(chan struct{})
+	go func() {
+		defer close(waiting)
+		if listener, err := listenInstance(path); err == nil {
+			listener.Close()
+		}
+	}()
+	select {
+	case <-waiting:
+		t.Fatal("listenInstance did not wait for the lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	<-waiting
+	if stale, err = net.Listen("unix", path); err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	var wg sync.WaitGroup
+	listeners := make(chan net.Listener, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			listener, err := listenInstance(path)
+			if err == nil {
+				listeners <- listener
+			} else if !errors.Is(err, errInstanceRunning) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(listeners)
+	if len(listeners) != 1 {
+		t.Fatalf("expected one instance, got %d", len(listeners))
+	}
+	listener := <-listeners
+	defer listener.Close()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("the running instance is unreachable: %v", err)
+	}
+	conn.Close()
+}
+
+// TestInstance_PrivateDirectory verifies the socket directory is narrowed
+// to this user and that a symlink in its place is refused.
+func TestInstance_PrivateDirectory(t *testing.T) {
+	path := testSocketPath(t)
+	dir := filepath.Dir(path)
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := listenInstance(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.Close()
+	if info, _ := os.Stat(dir); info.Mode().Perm() != 0700 {
+		t.Errorf("expected the directory narrowed to 0700, got %v", info.Mode().Perm())
+	}
+
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(t.TempDir(), link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenInstance(filepath.Join(link, "test.sock")); err == nil {
+		t.Error("expected a symlinked directory to be refused")
+	}
+}
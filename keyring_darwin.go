@@ -0,0 +1,87 @@
+package main
+
+/*
+#cgo LDFLAGS: -framework Security -framework CoreFoundation
+#include <Security/Security.h>
+#include <string.h>
+
+// keychainQuery returns a query for our generic password item.
+static CFMutableDictionaryRef keychainQuery(void) {
+    CFMutableDictionaryRef q = CFDictionaryCreateMutable(NULL, 0, &kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+    CFDictionarySetValue(q, kSecClass, kSecClassGenericPassword);
+    CFDictionarySetValue(q, kSecAttrService, CFSTR("clipboard-island"));
+    CFDictionarySetValue(q, kSecAttrAccount, CFSTR("history-key"));
+    return q;
+}
+
+// keychainGet copies up to size bytes of the stored key into buf and
+// returns the key's length, or -1 with *status set on failure.
+static int keychainGet(unsigned char *buf, int size, int *status) {
+    CFMutableDictionaryRef q = keychainQuery();
+    CFDictionarySetValue(q, kSecReturnData, kCFBooleanTrue);
+    CFDictionarySetValue(q, kSecMatchLimit, kSecMatchLimitOne);
+    CFTypeRef result = NULL;
+    *status = (int)SecItemCopyMatching(q, &result);
+    CFRelease(q);
+    if (*status != errSecSuccess) return -1;
+    int n = (int)CFDataGetLength((CFDataRef)result);
+    memcpy(buf, CFDataGetBytePtr((CFDataRef)result), n < size ? n : size);
+    CFRelease(result);
+    return n;
+}
+
+// keychainSet stores key, replacing any previous one, and returns the OSStatus.
+static int keychainSet(const unsigned char *key, int n) {
+    CFDataRef data = CFDataCreate(NULL, key, n);
+    CFMutableDictionaryRef q = keychainQuery();
+    CFDictionarySetValue(q, kSecValueData, data);
+    OSStatus status = SecItemAdd(q, NULL);
+    CFRelease(q);
+    if (status == errSecDuplicateItem) {
+        CFMutableDictionaryRef match = keychainQuery();
+        CFMutableDictionaryRef update = CFDictionaryCreateMutable(NULL, 0, &kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+        CFDictionarySetValue(update, kSecValueData, data);
+        status = SecItemUpdate(match, update);
+        CFRelease(update);
+        CFRelease(match);
+    }
+    CFRelease(data);
+    return (int)status;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// keychainKeyring keeps the history key in the login Keychain.
+type keychainKeyring struct{}
+
+func newSystemKeyring() keyring {
+	return keychainKeyring{}
+}
+
+func (keychainKeyring) Get() ([]byte, error) {
+	buf := make([]byte, 64)
+	var status C.int
+	n := C.keychainGet((*C.uchar)(unsafe.Pointer(&buf[0])), C.int(len(buf)), &status)
+	if n < 0 {
+		if status == C.errSecItemNotFound {
+			return nil, errKeyNotFound
+		}
+		return nil, fmt.Errorf("keychain error %d", int(status))
+	}
+	if int(n) > len(buf) {
+		return nil, fmt.Errorf("keychain key too long (%d bytes)", int(n))
+	}
+	return buf[:n], nil
+}
+
+func (keychainKeyring) Set(key []byte) error {
+	if status := C.keychainSet((*C.uchar)(unsafe.Pointer(&key[0])), C.int(len(key))); status != 0 {
+		return fmt.Errorf("keychain error %d", int(status))
+	}
+	return nil
+}
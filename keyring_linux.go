@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// secretToolKeyring keeps the history key in the Secret Service (GNOME
+// Keyring, KWallet) through secret-tool, hex-encoded since it stores text.
+type secretToolKeyring struct{}
+
+// keyringAttrs identify our item in the Secret Service.
+var keyringAttrs = []string{"service", "clipboard-island", "account", "history-key"}
+
+// newSystemKeyring returns the Secret Service keyring, or nil if
+// secret-tool is not installed.
+func newSystemKeyring() keyring {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil
+	}
+	return secretToolKeyring{}
+}
+
+func (secretToolKeyring) Get() ([]byte, error) {
+	cmd := exec.Command("secret-tool", append([]string{"lookup"}, keyringAttrs...)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() == 0 {
+			return nil, errKeyNotFound // lookup fails silently when nothing matches
+		}
+		return nil, fmt.Errorf("secret-tool lookup: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return hex.DecodeString(strings.TrimSpace(string(out)))
+}
+
+func (secretToolKeyring) Set(key []byte) error {
+	args := append([]string{"store", "--label=Clipboard Island history key"}, keyringAttrs...)
+	cmd := exec.Command("secret-tool", args...)
+	cmd.Stdin = strings.NewReader(hex.EncodeToString(key))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool store: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
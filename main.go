@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"errors"
 	"log"
 	"os"
 	"runtime"
@@ -9,7 +10,6 @@ import (
 
 	"github.com/wailsapp/wails/v3/pkg/application"
 	"github.com/wailsapp/wails/v3/pkg/icons"
-	"golang.design/x/hotkey"
 )
 
 //go:embed all:frontend/dist
@@ -21,12 +21,33 @@ func main() {
 		os.Setenv("PATH", os.Getenv("PATH")+":/usr/sbin")
 	}
 
-	appService := &App{}
+	// Only one instance runs; later launches hand their arguments to it
+	socketPath := instanceSocketPath()
+	if args := os.Args[1:]; wantsCLI(args) {
+		os.Exit(runCLI(socketPath, args, os.Stdin, os.Stdout, os.Stderr))
+	} else if len(args) > 0 {
+		log.Printf("[clipboard] Ignoring launch arguments %q", args)
+	}
+	listener, err := listenInstance(socketPath)
+	if errors.Is(err, errInstanceRunning) {
+		os.Exit(runCLI(socketPath, []string{"show"}, os.Stdin, os.Stdout, os.Stderr))
+	}
+	if err != nil {
+		log.Printf("[clipboard] single-instance socket unavailable, running anyway: %v", err)
+	}
 
 	// Initialize clipboard
-	if err := appService.initClipboard(); err != nil {
+	backend, err := newSystemBackend()
+	if err != nil {
 		log.Fatalf("[clipboard] failed to init clipboard: %v", err)
 	}
+	appService := NewApp(backend)
+	appService.settingsPath = getSettingsFilePath()
+	appService.settings = loadSettings(appService.settingsPath)
+	appService.excludes = compileExcludes(appService.settings.ExcludePatterns)
+	appService.dataDir = getDataDir()
+	appService.keyring = newSystemKeyring()
+	settings := appService.GetSettings()
 
 	wailsApp := application.New(application.Options{
 		Name:        "Clipboard",
@@ -41,20 +62,21 @@ func main() {
 			ActivationPolicy:                                 application.ActivationPolicyAccessory,
 			ApplicationShouldTerminateAfterLastWindowClosed: false,
 		},
+		// Quit, including the tray item, runs the ordered shutdown; signals
+		// are handled by handleSignals so it runs before Wails tears down.
+		OnShutdown:                  appService.shutdown,
+		DisableDefaultSignalHandler: true,
 	})
 
 	// ── Island window ─────────────────────────────────────────────────────────
-	const windowW = 380
-	const windowH = 370
-
 	window := wailsApp.Window.NewWithOptions(application.WebviewWindowOptions{
 		Title:           "Clipboard",
-		Width:           windowW,
-		Height:          windowH,
-		MinWidth:        windowW,
-		MaxWidth:        windowW,
-		MinHeight:       120,
-		MaxHeight:       800,
+		Width:           settings.WindowWidth,
+		Height:          settings.WindowHeight,
+		MinWidth:        settings.WindowWidth,
+		MaxWidth:        settings.WindowWidth,
+		MinHeight:       minWindowHeight,
+		MaxHeight:       maxWindowHeight,
 		Frameless:       true,
 		AlwaysOnTop:     true,
 		Hidden:          true,
@@ -68,20 +90,10 @@ func main() {
 
 	appService.window = window
 	appService.wailsApp = wailsApp
-
-	// Helper to show the island (used by both hotkey and tray menu)
-	showIsland := func() {
-		appService.capturePreviousApp()
-		cx, cy, sw, sh, scale := cursorAndScreen()
-		ww := windowW * scale
-		wh := windowH * scale
-		wx, wy := calcWindowPosition(cx, cy, ww, wh, sw, sh)
-		window.SetSize(windowW, windowH)
-		window.SetPosition(wx, wy)
-		window.Show()
-		window.Focus()
-		wailsApp.Event.Emit("hotkey")
+	appService.emit = func(name string, data any) {
+		wailsApp.Event.Emit(name, data)
 	}
+	appService.openURL = wailsApp.Browser.OpenURL
 
 	// ── Menu bar icon ─────────────────────────────────────────────────────────
 	tray := wailsApp.SystemTray.New()
@@ -89,36 +101,48 @@ func main() {
 		tray.SetTemplateIcon(icons.SystrayMacTemplate)
 	}
 	trayMenu := wailsApp.NewMenu()
-	trayMenu.Add("Show Clipboard  (\u2318\u21e7V)").OnClick(func(ctx *application.Context) {
-		showIsland()
+	appService.showMenuItem = trayMenu.Add(showMenuLabel(settings.Hotkey)).OnClick(func(ctx *application.Context) {
+		appService.showIsland()
 	})
+	appService.pauseMenuItem = trayMenu.Add("Pause Capture").OnClick(func(ctx *application.Context) {
+		appService.togglePause()
+	})
+	pauseFor := trayMenu.AddSubmenu("Pause For")
+	for _, preset := range pausePresets {
+		pauseFor.Add(preset.label).OnClick(func(ctx *application.Context) {
+			appService.PauseCapture(preset.minutes)
+		})
+	}
 	trayMenu.AddSeparator()
 	trayMenu.Add("Quit Clipboard").OnClick(func(ctx *application.Context) {
 		wailsApp.Quit()
 	})
 	tray.SetMenu(trayMenu)
-	tray.SetTooltip("Clipboard")
+	tray.SetTooltip(pauseTooltip(PauseStatus{}))
+	appService.tray = tray
 
 	// ── Global hotkey ─────────────────────────────────────────────────────────
 	go func() {
-		hk := hotkey.New([]hotkey.Modifier{hotkey.ModCmd, hotkey.ModShift}, hotkey.KeyV)
-		if err := hk.Register(); err != nil {
+		if err := appService.startHotkeys(); err != nil {
 			log.Printf("[clipboard] hotkey register failed (grant Accessibility): %v", err)
-			return
-		}
-		log.Println("[clipboard] Hotkey Cmd+Shift+V active")
-		for range hk.Keydown() {
-			showIsland()
 		}
 	}()
 
-	// Load saved history (pinned items)
+	// Load saved history (pinned items, or everything with persistHistory)
 	appService.loadHistory()
+	appService.restorePause()
+
+	// Watch the clipboard, and settings.json for edits, until shutdown
+	appService.runBackground(appService.watchClipboard, appService.watchSettings)
+	appService.handleSignals(wailsApp.Quit)
+	if listener != nil {
+		appService.instance = listener
+		go appService.serveInstance(listener)
+	}
 
-	// Start clipboard watching in background
-	go appService.watchClipboard()
-
-	if err := wailsApp.Run(); err != nil {
+	err = wailsApp.Run()
+	appService.shutdown() // No-op if OnShutdown already ran
+	if err != nil {
 		log.Fatal(err)
 	}
 }
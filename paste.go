@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PasteMode selects which representation of a clip is pasted.
+type PasteMode string
+
+const (
+	PasteOriginal   PasteMode = "original"   // Text with its rich formats, or the image
+	PastePlain      PasteMode = "plain"      // Text only, dropping HTML/RTF/files
+	PasteSingleLine PasteMode = "singleLine" // Text only, trimmed with all whitespace runs collapsed to one space
+)
+
+// validate reports whether m is a known paste mode.
+func (m PasteMode) validate() error {
+	switch m {
+	case PasteOriginal, PastePlain, PasteSingleLine:
+		return nil
+	}
+	return fmt.Errorf("unknown paste mode %q", m)
+}
+
+// PasteItemAs pastes the item with the given ID in the given mode; an empty
+// mode uses Settings.PasteMode. Images always paste as images.
+// Exported for Wails binding.
+func (a *App) PasteItemAs(id string, mode PasteMode) error {
+	if mode == "" {
+		mode = a.GetSettings().PasteMode
+	}
+	if err := mode.validate(); err != nil {
+		return err
+	}
+	return a.pasteItem(id, mode, nil)
+}
+
+// pasteText returns the text of item as pasted in mode, and the rich
+// formats to paste with it.
+func pasteText(item ClipItem, mode PasteMode) (string, map[ClipFormat][]byte) {
+	switch mode {
+	case PastePlain:
+		return item.Text, nil
+	case PasteSingleLine:
+		return strings.Join(strings.Fields(item.Text), " "), nil
+	}
+	return item.Text, item.Formats
+}
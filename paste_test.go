@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// pastedItem pastes the first history item in mode and returns what the
+// backend was left holding.
+func pastedItem(t *testing.T, app *App, fake *fakeBackend, mode PasteMode) map[ClipFormat][]byte {
+	t.Helper()
+	if err := app.PasteItemAs(app.history[0].ID, mode); err != nil {
+		t.Fatalf("PasteItemAs(%q) failed: %v", mode, err)
+	}
+	select {
+	case <-fake.pasted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("paste was never simulated")
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.data
+}
+
+// TestPasteItemAs_Modes verifies each mode writes the expected representations.
+func TestPasteItemAs_Modes(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	app.addTextItem("  hello\n\tbig   world ", map[ClipFormat][]byte{FormatHTML: []byte("<p>hello</p>")})
+
+	data := pastedItem(t, app, fake, PasteOriginal)
+	if string(data[FormatText]) != "hello\n\tbig   world" || string(data[FormatHTML]) != "<p>hello</p>" {
+		t.Errorf("original: got %q", data)
+	}
+
+	data = pastedItem(t, app, fake, PastePlain)
+	if len(data) != 1 || string(data[FormatText]) != "hello\n\tbig   world" {
+		t.Errorf("plain: got %q", data)
+	}
+
+	data = pastedItem(t, app, fake, PasteSingleLine)
+	if len(data) != 1 || string(data[FormatText]) != "hello big world" {
+		t.Errorf("singleLine: got %q", data)
+	}
+}
+
+// TestPasteItemAs_DefaultFromSettings verifies an empty mode and SelectItem
+// follow Settings.PasteMode.
+func TestPasteItemAs_DefaultFromSettings(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	app.settings.PasteMode = PastePlain
+	app.addTextItem("text", map[ClipFormat][]byte{FormatRTF: []byte(`{\rtf1 text}`)})
+
+	if data := pastedItem(t, app, fake, ""); len(data) != 1 {
+		t.Errorf("expected plain text only, got %q", data)
+	}
+
+	app.SelectItem(app.history[0].ID)
+	<-fake.pasted
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.data) != 1 {
+		t.Errorf("SelectItem: expected plain text only, got %q", fake.data)
+	}
+}
+
+// TestPasteItemAs_UnknownMode verifies bad modes are rejected without pasting.
+func TestPasteItemAs_UnknownMode(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	app.addItem("text")
+
+	if err := app.PasteItemAs(app.history[0].ID, "shouting"); err == nil {
+		t.Fatal("expected error for unknown mode")
+	}
+	if fake.ChangeCount() != 0 {
+		t.Error("clipboard was written despite the error")
+	}
+}
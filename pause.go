@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EventPauseChanged is emitted with the new PauseStatus whenever capture is
+// paused or resumed.
+const EventPauseChanged = "pause:changed"
+
+// maxPauseMinutes bounds PauseCapture; longer pauses are "until resumed".
+const maxPauseMinutes = 7 * 24 * 60
+
+// PauseStatus says whether capture is paused, and until when. A zero Until
+// means until resumed.
+type PauseStatus struct {
+	Paused bool      `json:"paused"`
+	Until  time.Time `json:"until,omitzero"`
+}
+
+// pausePresets are the durations offered in the tray's "Pause For" menu.
+var pausePresets = []struct {
+	label   string
+	minutes int
+}{
+	{"10 Minutes", 10},
+	{"30 Minutes", 30},
+	{"1 Hour", 60},
+	{"4 Hours", 240},
+}
+
+// PauseCapture stops capturing clipboard changes for the given number of
+// minutes, or until ResumeCapture when minutes is 0. Exported for Wails binding.
+func (a *App) PauseCapture(minutes int) error {
+	if minutes < 0 || minutes > maxPauseMinutes {
+		return fmt.Errorf("minutes must be between 0 and %d, got %d", maxPauseMinutes, minutes)
+	}
+	a.pause(time.Duration(minutes) * time.Minute)
+	return nil
+}
+
+// ResumeCapture ends a pause. Exported for Wails binding.
+func (a *App) ResumeCapture() {
+	a.setPause(PauseStatus{})
+}
+
+// GetPauseStatus returns the current pause state. Exported for Wails binding.
+func (a *App) GetPauseStatus() PauseStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.pausedAt(a.watchClock().Now()) {
+		return PauseStatus{}
+	}
+	return a.pauseState
+}
+
+// togglePause pauses capture until resumed, or resumes it. Bound to
+// Settings.TogglePauseHotkey and the tray menu.
+func (a *App) togglePause() {
+	if a.GetPauseStatus().Paused {
+		a.ResumeCapture()
+	} else {
+		a.pause(0)
+	}
+}
+
+// pause pauses capture for d, or until resumed when d is 0.
+func (a *App) pause(d time.Duration) {
+	status := PauseStatus{Paused: true}
+	if d > 0 {
+		status.Until = a.watchClock().Now().Add(d)
+	}
+	a.setPause(status)
+}
+
+// pausedAt reports whether capture is paused at now, as read from
+// watchClock like every other pause check.
+// Caller must hold a.mu.
+func (a *App) pausedAt(now time.Time) bool {
+	return a.pauseState.Paused && (a.pauseState.Until.IsZero() || now.Before(a.pauseState.Until))
+}
+
+// setPause makes status current: it arms the automatic resume, saves the
+// state when Settings.PersistPause is on, and updates the tray and island.
+func (a *App) setPause(status PauseStatus) {
+	a.mu.Lock()
+	a.pauseState = status
+	if a.pauseTimer != nil {
+		a.pauseTimer.Stop()
+		a.pauseTimer = nil
+	}
+	if status.Paused && !status.Until.IsZero() {
+		a.pauseTimer = time.AfterFunc(status.Until.Sub(a.watchClock().Now()), a.resumeIfExpired)
+	}
+	a.mu.Unlock()
+
+	switch {
+	case !status.Paused:
+		log.Println("[clipboard] Capture resumed")
+	case status.Until.IsZero():
+		log.Println("[clipboard] Capture paused")
+	default:
+		log.Printf("[clipboard] Capture paused until %s", status.Until.Format(time.Kitchen))
+	}
+	a.savePauseState()
+	a.showPauseState(status)
+}
+
+// resumeIfExpired resumes capture once a timed pause has run out.
+func (a *App) resumeIfExpired() {
+	a.mu.Lock()
+	expired := a.pauseState.Paused && !a.pausedAt(a.watchClock().Now())
+	a.mu.Unlock()
+	if expired {
+		a.ResumeCapture()
+	}
+}
+
+// showPauseState reflects status in the tray menu and tooltip and tells
+// the frontend.
+func (a *App) showPauseState(status PauseStatus) {
+	if a.pauseMenuItem != nil {
+		if status.Paused {
+			a.pauseMenuItem.SetLabel("Resume Capture")
+		} else {
+			a.pauseMenuItem.SetLabel("Pause Capture")
+		}
+	}
+	if a.tray != nil {
+		a.tray.SetTooltip(pauseTooltip(status))
+	}
+	if a.emit != nil {
+		a.emit(EventPauseChanged, status)
+	}
+}
+
+// pauseTooltip returns the tray tooltip for status.
+func pauseTooltip(status PauseStatus) string {
+	switch {
+	case !status.Paused:
+		return "Clipboard"
+	case status.Until.IsZero():
+		return "Clipboard (capture paused)"
+	default:
+		return "Clipboard (capture paused until " + status.Until.Format(time.Kitchen) + ")"
+	}
+}
+
+// pausePath returns the file that keeps a pause across restarts, or ""
+// when persistence is disabled.
+func (a *App) pausePath() string {
+	if a.dataDir == "" {
+		return ""
+	}
+	return filepath.Join(a.dataDir, "pause.json")
+}
+
+// savePauseState writes the pause to disk while Settings.PersistPause is on,
+// and removes the file otherwise.
+func (a *App) savePauseState() {
+	path := a.pausePath()
+	if path == "" {
+		return
+	}
+	a.mu.Lock()
+	status, persist := a.pauseState, a.settings.PersistPause
+	a.mu.Unlock()
+
+	if !persist || !status.Paused {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("[clipboard] failed to remove pause state: %v", err)
+		}
+		return
+	}
+	data, err := json.Marshal(status)
+	if err == nil {
+		err = os.MkdirAll(a.dataDir, 0755)
+	}
+	if err == nil {
+		err = writeFileAtomic(path, data, 0600)
+	}
+	if err != nil {
+		log.Printf("[clipboard] failed to save pause state: %v", err)
+	}
+}
+
+// restorePause resumes a pause saved by a previous run, if
+// Settings.PersistPause is on and it has not run out.
+func (a *App) restorePause() {
+	path := a.pausePath()
+	if path == "" || !a.GetSettings().PersistPause {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[clipboard] failed to read pause state: %v", err)
+		}
+		return
+	}
+	var status PauseStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		log.Printf("[clipboard] ignoring pause state: %v", err)
+		return
+	}
+	if status.Paused && (status.Until.IsZero() || a.watchClock().Now().Before(status.Until)) {
+		a.setPause(status)
+	} else {
+		a.setPause(PauseStatus{}) // Ran out while we were not running
+	}
+}
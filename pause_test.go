@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestPauseCapture_Timed verifies a timed pause blocks capture, then
+// resumes by itself and tells the frontend.
+func TestPauseCapture_Timed(t *testing.T) {
+	clip := newFakeBackend()
+	app := NewApp(clip)
+	var mu sync.Mutex
+	var events []PauseStatus
+	app.emit = func(name string, data any) {
+		if name == EventPauseChanged {
+			mu.Lock()
+			events = append(events, data.(PauseStatus))
+			mu.Unlock()
+		}
+	}
+	state := &watchState{lastCount: clip.ChangeCount()}
+
+	app.setPause(PauseStatus{Paused: true, Until: time.Now().Add(50 * time.Millisecond)})
+	if status := app.GetPauseStatus(); !status.Paused || status.Until.IsZero() {
+		t.Fatalf("expected a timed pause, got %+v", status)
+	}
+	clip.copyText("during")
+	app.pollClipboard(state)
+	if len(app.GetHistory()) != 0 {
+		t.Fatal("captured while paused")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for app.GetPauseStatus().Paused {
+		if time.Now().After(deadline) {
+			t.Fatal("pause did not end")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	clip.copyText("after")
+	app.pollClipboard(state)
+	if h := app.GetHistory(); len(h) != 1 || h[0].Text != "after" {
+		t.Errorf("expected capture after the pause, got %+v", h)
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(events)
+		last := PauseStatus{Paused: true}
+		if n > 0 {
+			last = events[n-1]
+		}
+		mu.Unlock()
+		if n == 2 && !last.Paused {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected pause and resume events, got %d ending with %+v", n, last)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestPauseCapture_Clock verifies pauses are timed by the watcher's clock:
+// set, checked and restored against it.
+func TestPauseCapture_Clock(t *testing.T) {
+	clip := newFakeBackend()
+	clk := newFakeClock()
+	app := newPersistentApp(t, t.TempDir(), false)
+	app.clip, app.clock = clip, clk
+	app.settings.PersistPause = true
+	state := &watchState{lastCount: clip.ChangeCount()}
+
+	if err := app.PauseCapture(10); err != nil {
+		t.Fatal(err)
+	}
+	if until := app.GetPauseStatus().Until; !until.Equal(clk.Now().Add(10 * time.Minute)) {
+		t.Fatalf("expected the pause to end 10 minutes from the clock's now, got %v", until)
+	}
+	clip.copyText("during")
+	app.pollClipboard(state)
+	if len(app.GetHistory()) != 0 {
+		t.Fatal("captured while paused")
+	}
+
+	restored := newPersistentApp(t, app.dataDir, false)
+	restored.clock = clk
+	restored.settings.PersistPause = true
+	restored.restorePause()
+	if !restored.GetPauseStatus().Paused {
+		t.Error("expected the pause restored by the clock's now")
+	}
+
+	clk.Advance(11 * time.Minute)
+	if app.GetPauseStatus().Paused {
+		t.Error("expected the pause over once the clock passed it")
+	}
+	clip.copyText("after")
+	app.pollClipboard(state)
+	if h := app.GetHistory(); len(h) != 1 || h[0].Text != "after" {
+		t.Errorf("expected capture after the pause, got %+v", h)
+	}
+}
+
+// TestPauseCapture_Validation verifies out-of-range durations are rejected
+// and 0 pauses until resumed.
+func TestPauseCapture_Validation(t *testing.T) {
+	app := NewApp(newFakeBackend())
+	for _, minutes := range []int{-1, maxPauseMinutes + 1} {
+		if err := app.PauseCapture(minutes); err == nil {
+			t.Errorf("PauseCapture(%d): expected error", minutes)
+		}
+	}
+	if app.GetPauseStatus().Paused {
+		t.Fatal("rejected pause took effect")
+	}
+
+	if err := app.PauseCapture(0); err != nil {
+		t.Fatal(err)
+	}
+	if status := app.GetPauseStatus(); !status.Paused || !status.Until.IsZero() {
+		t.Errorf("expected a pause until resumed, got %+v", status)
+	}
+	app.togglePause()
+	if app.GetPauseStatus().Paused {
+		t.Error("togglePause did not resume")
+	}
+}
+
+// TestPauseCapture_Persist verifies a pause survives a restart only with
+// PersistPause, and an expired one is dropped.
+func TestPauseCapture_Persist(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, false)
+	app.settings.PersistPause = true
+	if err := app.PauseCapture(30); err != nil {
+		t.Fatal(err)
+	}
+	want := app.GetPauseStatus()
+
+	restored := newPersistentApp(t, dir, false)
+	restored.settings.PersistPause = true
+	restored.restorePause()
+	if got := restored.GetPauseStatus(); !got.Paused || !got.Until.Equal(want.Until) {
+		t.Errorf("expected %+v restored, got %+v", want, got)
+	}
+	restored.ResumeCapture()
+	if _, err := os.Stat(restored.pausePath()); !os.IsNotExist(err) {
+		t.Errorf("expected pause.json removed on resume, got %v", err)
+	}
+
+	app.setPause(PauseStatus{Paused: true, Until: time.Now().Add(-time.Minute)})
+	expired := newPersistentApp(t, dir, false)
+	expired.settings.PersistPause = true
+	expired.restorePause()
+	if expired.GetPauseStatus().Paused {
+		t.Error("expired pause was restored")
+	}
+
+	app.pause(0)
+	ignored := newPersistentApp(t, dir, false)
+	ignored.restorePause()
+	if ignored.GetPauseStatus().Paused {
+		t.Error("pause restored without PersistPause")
+	}
+}
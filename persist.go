@@ -0,0 +1,556 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+// saveDebounce is how long history must stay unchanged before it is written,
+// so a burst of copies turns into a single write.
+const saveDebounce = 500 * time.Millisecond
+
+// getDataDir returns the directory holding history.json and related files.
+func getDataDir() string {
+	return filepath.Join(xdg.DataHome, "clipboard-island")
+}
+
+// historyPath returns the path to the history file, or "" when persistence
+// is disabled (an App without a data directory, as in tests).
+func (a *App) historyPath() string {
+	if a.dataDir == "" {
+		return ""
+	}
+	return filepath.Join(a.dataDir, "history.json")
+}
+
+// scheduleSave marks history dirty and (re)starts the debounce timer.
+func (a *App) scheduleSave() {
+	a.saveMu.Lock()
+	defer a.saveMu.Unlock()
+	a.saveDirty = true
+	if a.saveTimer != nil {
+		a.saveTimer.Stop()
+	}
+	a.saveTimer = time.AfterFunc(saveDebounce, a.saveIfDirty)
+}
+
+// flushSave writes pending changes now instead of waiting for the debounce.
+// It returns once the data is on disk, including a write already in flight.
+func (a *App) flushSave() {
+	a.saveMu.Lock()
+	if a.saveTimer != nil {
+		a.saveTimer.Stop()
+		a.saveTimer = nil
+	}
+	a.saveMu.Unlock()
+	a.saveIfDirty()
+}
+
+// saveIfDirty writes history if anything changed since the last write.
+// Writes are serialized by saveWriteMu.
+func (a *App) saveIfDirty() {
+	a.saveWriteMu.Lock()
+	defer a.saveWriteMu.Unlock()
+
+	a.saveMu.Lock()
+	dirty := a.saveDirty
+	a.saveDirty = false
+	a.saveMu.Unlock()
+
+	if dirty {
+		a.saveHistory()
+	}
+}
+
+// saveHistory writes history to disk as JSON: every item when
+// Settings.PersistHistory is on, otherwise only pinned items. Sensitive
+// items are never written. With Settings.EncryptHistory on, the file and
+// images are encrypted; turning it on or off converts what is on disk.
+// Caller must hold a.saveWriteMu.
+func (a *App) saveHistory() {
+	path := a.historyPath()
+	if path == "" {
+		return
+	}
+
+	a.mu.Lock()
+	if a.loadFailed {
+		a.mu.Unlock()
+		log.Println("[clipboard] Not saving history: the file on disk could not be read")
+		return
+	}
+	encrypt := a.settings.EncryptHistory
+	var items []ClipItem
+	for _, item := range a.history {
+		if item.Sensitive {
+			continue // Secrets stay in memory only
+		}
+		if item.Pinned || a.settings.PersistHistory {
+			items = append(items, item)
+		}
+	}
+	a.mu.Unlock()
+
+	// Create directory if needed
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Printf("[clipboard] failed to create data directory: %v", err)
+		return
+	}
+
+	// Move image bytes out of the JSON into the blob store. Every image
+	// written takes a reference; the previous file's references are dropped
+	// once it rotates out of the backups, which deletes blobs no longer used.
+	blobs := a.imageBlobs()
+	if err := blobs.setEncrypted(encrypt); err != nil {
+		a.setStorageError(fmt.Errorf("convert images: %w", err))
+		return
+	}
+	var saved []string
+	for i := range items {
+		if items[i].Type != TypeImage {
+			continue
+		}
+		hash, err := a.storeImage(blobs, items[i])
+		if err != nil {
+			log.Printf("[clipboard] failed to save image: %v", err)
+			releaseAll(blobs, saved)
+			return
+		}
+		saved = append(saved, hash)
+		items[i].ImageHash = hash
+		items[i].ImageData = ""
+	}
+
+	// Write to file, keeping the previous one as a backup
+	data, err := encodeHistory(items)
+	if err != nil {
+		log.Printf("[clipboard] failed to marshal history: %v", err)
+		releaseAll(blobs, saved)
+		return
+	}
+	if encrypt {
+		if data, err = a.cryptor().seal(data); err != nil {
+			a.setStorageError(fmt.Errorf("encrypt history: %w", err))
+			releaseAll(blobs, saved)
+			return
+		}
+	}
+
+	if now := a.watchClock().Now(); a.lastBackup.IsZero() || now.Sub(a.lastBackup) >= historyBackupInterval {
+		rotated, err := rotateBackups(path)
+		if err != nil {
+			log.Printf("[clipboard] failed to back up history file: %v", err)
+		}
+		if rotated {
+			a.rotateBlobRefs(blobs)
+			a.lastBackup = now
+		}
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		a.setStorageError(fmt.Errorf("write history file: %w", err))
+		releaseAll(blobs, saved)
+		return
+	}
+
+	a.setBackupsEncrypted(path, encrypt)
+
+	releaseAll(blobs, a.savedBlobs[0])
+	a.savedBlobs[0] = saved
+	a.setStorageStatus(StorageStatus{Encrypted: encrypt})
+}
+
+// setBackupsEncrypted brings the backups and set-aside file of path in line
+// with Settings.EncryptHistory, on the first save and whenever it changes,
+// so plaintext history does not outlive turning encryption on. A file that
+// cannot be sealed is deleted instead.
+// Caller must hold a.saveWriteMu.
+func (a *App) setBackupsEncrypted(path string, on bool) {
+	if a.backupsModeSet && a.backupsEncrypted == on {
+		return
+	}
+	a.convertOrRemove(path+".corrupt", on)
+	for n := 1; n <= historyBackups; n++ {
+		if a.convertOrRemove(backupPath(path, n), on) {
+			releaseAll(a.imageBlobs(), a.savedBlobs[n])
+			a.savedBlobs[n] = nil
+		}
+	}
+	a.backupsEncrypted, a.backupsModeSet = on, true
+}
+
+// convertOrRemove converts a history file with convertHistoryFile, deleting
+// it if it cannot be sealed, and reports whether it deleted it.
+// Caller must hold a.saveWriteMu.
+func (a *App) convertOrRemove(path string, encrypt bool) bool {
+	err := a.convertHistoryFile(path, encrypt)
+	if err == nil {
+		return false
+	}
+	if !encrypt {
+		log.Printf("[clipboard] failed to decrypt %s: %v", filepath.Base(path), err)
+		return false
+	}
+	log.Printf("[clipboard] failed to encrypt %s, deleting it: %v", filepath.Base(path), err)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("[clipboard] failed to delete %s: %v", filepath.Base(path), err)
+		return false
+	}
+	return true
+}
+
+// convertHistoryFile seals or opens one history file in place, if it is
+// kept the other way.
+// Caller must hold a.saveWriteMu.
+func (a *App) convertHistoryFile(path string, encrypt bool) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil || isEncrypted(data) == encrypt {
+		return err
+	}
+	if encrypt {
+		data, err = a.cryptor().seal(data)
+	} else {
+		data, err = a.cryptor().open(data)
+	}
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// imageBlobs returns the blob store for persisted images.
+// Caller must hold a.saveWriteMu.
+func (a *App) imageBlobs() *blobStore {
+	if a.blobs == nil {
+		a.blobs = newBlobStore(a.imagesDir())
+		a.blobs.crypt = a.cryptor()
+	}
+	return a.blobs
+}
+
+// cryptor returns the cryptor for history files, keyed by the system
+// keyring or the passphrase in passphraseEnv.
+// Caller must hold a.saveWriteMu.
+func (a *App) cryptor() *cryptor {
+	if a.crypt == nil {
+		a.crypt = newCryptor(a.keyring, os.Getenv(passphraseEnv))
+	}
+	return a.crypt
+}
+
+// storeImage takes a blob reference for an image item, decoding and writing
+// its bytes only when the store does not have them yet.
+func (a *App) storeImage(blobs *blobStore, item ClipItem) (string, error) {
+	if item.ImageHash != "" && blobs.acquire(item.ImageHash) {
+		return item.ImageHash, nil
+	}
+	data, err := decodeBase64(item.ImageData)
+	if err != nil {
+		return "", err
+	}
+	return blobs.put(data)
+}
+
+// acquireAll takes a reference per hash to a blob that is stored, and
+// returns the hashes it took one for.
+func acquireAll(blobs *blobStore, hashes []string) []string {
+	var acquired []string
+	for _, hash := range hashes {
+		if blobs.acquire(hash) {
+			acquired = append(acquired, hash)
+		}
+	}
+	return acquired
+}
+
+// imageHashes returns the image hashes items point at.
+func imageHashes(items []ClipItem) []string {
+	var hashes []string
+	for _, item := range items {
+		if item.ImageHash != "" {
+			hashes = append(hashes, item.ImageHash)
+		}
+	}
+	return hashes
+}
+
+// rotateBlobRefs follows a rotateBackups: the oldest backup's references
+// are dropped and the newest backup takes over history.json's, which takes
+// a second set until the new file replaces it.
+// Caller must hold a.saveWriteMu.
+func (a *App) rotateBlobRefs(blobs *blobStore) {
+	releaseAll(blobs, a.savedBlobs[historyBackups])
+	copy(a.savedBlobs[1:], a.savedBlobs[:historyBackups])
+	a.savedBlobs[0] = acquireAll(blobs, a.savedBlobs[1])
+}
+
+// retainBlobs takes the references of the history file at path and of its
+// backups, so images a backup may yet be restored with are kept. items are
+// those already read from used. It reports whether every file could be
+// read; if not, images still in use may hold no reference.
+// Caller must hold a.saveWriteMu.
+func (a *App) retainBlobs(blobs *blobStore, path, used string, items []ClipItem) bool {
+	complete := true
+	for n := 0; n <= historyBackups; n++ {
+		file := path
+		if n > 0 {
+			file = backupPath(path, n)
+		}
+		fileItems := items
+		if file != used {
+			var err error
+			if fileItems, _, err = a.readHistoryFile(file); err != nil {
+				complete = complete && errors.Is(err, os.ErrNotExist)
+				continue
+			}
+		}
+		a.savedBlobs[n] = acquireAll(blobs, imageHashes(fileItems))
+	}
+	return complete
+}
+
+// releaseAll drops one blob reference per hash.
+func releaseAll(blobs *blobStore, hashes []string) {
+	for _, hash := range hashes {
+		if err := blobs.release(hash); err != nil {
+			log.Printf("[clipboard] failed to release image %s: %v", hash, err)
+		}
+	}
+}
+
+// loadHistory reads saved items from disk on startup, in their saved order.
+// Unpinned items are only restored when Settings.PersistHistory is on, so
+// turning the setting off drops them on the next launch. An unreadable
+// file is replaced by the newest backup that reads; a history that cannot
+// be decrypted puts storage in an error state, see failLoad.
+func (a *App) loadHistory() {
+	path := a.historyPath()
+	if path == "" {
+		return
+	}
+
+	a.saveWriteMu.Lock()
+	defer a.saveWriteMu.Unlock()
+
+	saved, encrypted, used, err := a.readLatestHistory(path)
+	if errors.Is(err, errCannotDecrypt) || errors.Is(err, errNewerHistory) {
+		a.mu.Lock()
+		a.failLoad(err, encrypted)
+		a.mu.Unlock()
+		return
+	}
+	rewrite := false
+	if used != path {
+		setAside(path) // Keep a corrupt file for inspection, out of the backup rotation
+		if err != nil {
+			log.Printf("[clipboard] No readable history, starting empty: %v", err)
+			return
+		}
+		if used == "" {
+			return // Nothing saved yet
+		}
+		log.Printf("[clipboard] Restored history from %s", filepath.Base(used))
+		rewrite = true
+	}
+	blobs := a.imageBlobs()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.imageIndex == nil {
+		a.imageIndex = make(map[string]string)
+	}
+
+	pinned := 0
+	for _, item := range saved {
+		if !item.Pinned && !a.settings.PersistHistory {
+			continue
+		}
+		if item.ImageHash != "" {
+			imgData, err := blobs.get(item.ImageHash)
+			if errors.Is(err, os.ErrNotExist) {
+				log.Printf("[clipboard] dropping image item %s: %v", item.ID, err)
+				continue
+			}
+			if err != nil {
+				a.failLoad(fmt.Errorf("read image %s: %w", item.ImageHash, err), encrypted)
+				return // Skip the sweep below, which would delete the unreadable images
+			}
+			item.ImageData = "data:image/png;base64," + encodeBase64(imgData)
+		} else if item.ImageData != "" {
+			// Older files inline the image; hash it so dedup works
+			if imgData, err := decodeBase64(item.ImageData); err == nil {
+				item.ImageHash = hashBytes(imgData)
+			}
+		}
+		if item.Text == "" && item.ImageData == "" {
+			continue
+		}
+		// Files written before items had IDs get one on load
+		if item.ID == "" {
+			item.ID = newItemID()
+		}
+		// Likewise for items saved before text was classified
+		if item.Type == TypeText && item.Kind == "" {
+			item.Kind, item.Language = classify(item.Text)
+		}
+		// Files written before secrets were detected may hold some; they
+		// stay for this session but are not saved again
+		if item.Type == TypeText && !item.Sensitive {
+			if secret := detectSecret(item.Text); secret != "" {
+				item.Sensitive, item.SecretKind = true, secret
+				item.ExpiresAt = time.Now().Add(time.Duration(a.settings.withDefaults().SensitiveTTLSeconds) * time.Second)
+			}
+		}
+		if item.Type == TypeImage {
+			a.imageIndex[item.ImageHash] = item.ID
+		}
+		if item.Pinned {
+			pinned++
+		}
+		a.history = append(a.history, item)
+	}
+
+	log.Printf("[clipboard] Loaded %d items (%d pinned)", len(a.history), pinned)
+	a.storage = StorageStatus{Encrypted: encrypted}
+	if encrypted != a.settings.EncryptHistory {
+		log.Printf("[clipboard] Rewriting history (encrypted: %v)", a.settings.EncryptHistory)
+		rewrite = true
+	}
+	if rewrite {
+		a.scheduleSave()
+	}
+
+	// Sweep blobs left behind by a crash, unless a backup that could not be
+	// read may still point at some
+	if !a.retainBlobs(blobs, path, used, saved) {
+		log.Println("[clipboard] Skipping image cleanup: a history backup could not be read")
+		return
+	}
+	if n, err := blobs.gc(); err != nil {
+		log.Printf("[clipboard] image cleanup failed: %v", err)
+	} else if n > 0 {
+		log.Printf("[clipboard] Removed %d orphaned images", n)
+	}
+}
+
+// readLatestHistory reads the history file, or the newest backup that
+// reads if it cannot be. used is the file read, "" if there is none; err
+// is the first failure, preferring one that forbids overwriting the file.
+// Caller must hold a.saveWriteMu.
+func (a *App) readLatestHistory(path string) (items []ClipItem, encrypted bool, used string, err error) {
+	var firstErr error
+	firstEncrypted := false
+	candidates := []string{path}
+	for n := 1; n <= historyBackups; n++ {
+		candidates = append(candidates, backupPath(path, n))
+	}
+	for _, candidate := range candidates {
+		items, encrypted, err := a.readHistoryFile(candidate)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[clipboard] cannot read %s: %v", filepath.Base(candidate), err)
+			blocking := errors.Is(err, errCannotDecrypt) || errors.Is(err, errNewerHistory)
+			if firstErr == nil || blocking && !errors.Is(firstErr, errCannotDecrypt) && !errors.Is(firstErr, errNewerHistory) {
+				firstErr, firstEncrypted = err, encrypted
+			}
+			continue
+		}
+		return items, encrypted, candidate, nil
+	}
+	return nil, firstEncrypted, "", firstErr
+}
+
+// readHistoryFile decrypts and decodes one history file.
+// Caller must hold a.saveWriteMu.
+func (a *App) readHistoryFile(path string) ([]ClipItem, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	encrypted := isEncrypted(data)
+	if encrypted {
+		if data, err = a.cryptor().open(data); err != nil {
+			return nil, true, fmt.Errorf("%w: %w", errCannotDecrypt, err)
+		}
+	}
+	items, err := decodeHistory(data)
+	return items, encrypted, err
+}
+
+// setAside renames an unreadable history file to <path>.corrupt, readable
+// by the owner only.
+func setAside(path string) {
+	if err := os.Rename(path, path+".corrupt"); err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[clipboard] failed to set aside %s: %v", filepath.Base(path), err)
+		}
+		return
+	}
+	if err := os.Chmod(path+".corrupt", 0600); err != nil {
+		log.Printf("[clipboard] failed to restrict %s: %v", filepath.Base(path)+".corrupt", err)
+	}
+}
+
+// imagesDir returns the directory of the image blob store.
+func (a *App) imagesDir() string {
+	return filepath.Join(a.dataDir, "images")
+}
+
+// EventStorageChanged is emitted with the new StorageStatus after every save
+// that changes it.
+const EventStorageChanged = "storage:changed"
+
+// StorageStatus describes the history on disk. Error is set while the
+// history cannot be read or written.
+type StorageStatus struct {
+	Encrypted bool   `json:"encrypted"`
+	Error     string `json:"error,omitempty"`
+}
+
+// GetStorageStatus returns the state of the history on disk.
+// Exported for Wails binding.
+func (a *App) GetStorageStatus() StorageStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.storage
+}
+
+// failLoad records that the history on disk could not be read. Saving stays
+// disabled for the rest of the session so the unreadable file (say, one
+// encrypted with a key that is not available right now) is not replaced.
+// Caller must hold a.mu.
+func (a *App) failLoad(err error, encrypted bool) {
+	log.Printf("[clipboard] %v; history will not be saved this session", err)
+	a.loadFailed = true
+	a.storage = StorageStatus{Encrypted: encrypted, Error: err.Error() + "; history will not be saved until the app restarts"}
+}
+
+// setStorageError reports a failed save.
+func (a *App) setStorageError(err error) {
+	log.Printf("[clipboard] failed to save history: %v", err)
+	a.mu.Lock()
+	status := StorageStatus{Encrypted: a.storage.Encrypted, Error: err.Error()}
+	a.mu.Unlock()
+	a.setStorageStatus(status)
+}
+
+// setStorageStatus records status and tells the frontend if it changed.
+func (a *App) setStorageStatus(status StorageStatus) {
+	a.mu.Lock()
+	changed := a.storage != status
+	a.storage = status
+	a.mu.Unlock()
+	if changed && a.emit != nil {
+		a.emit(EventStorageChanged, status)
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newPersistentApp returns an App that persists into a temporary directory.
+func newPersistentApp(t *testing.T, dir string, persistAll bool) *App {
+	t.Helper()
+	app := &App{dataDir: dir}
+	app.settings.PersistHistory = persistAll
+	return app
+}
+
+// TestPersist_PinnedOnlyByDefault verifies only pinned items are written without PersistHistory.
+func TestPersist_PinnedOnlyByDefault(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, false)
+	app.addItem("keep")
+	app.addItem("drop")
+	app.TogglePin(app.history[1].ID)
+	app.flushSave()
+
+	restored := newPersistentApp(t, dir, false)
+	restored.loadHistory()
+
+	if len(restored.history) != 1 || restored.history[0].Text != "keep" || !restored.history[0].Pinned {
+		t.Fatalf("expected only pinned 'keep', got %+v", restored.history)
+	}
+}
+
+// TestPersist_FullHistoryRoundTrip verifies order and pinned state survive a restart.
+func TestPersist_FullHistoryRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, true)
+	app.addItem("third")
+	app.addItem("second")
+	app.addItem("first")
+	app.TogglePin(app.history[1].ID) // "second"
+	app.flushSave()
+
+	restored := newPersistentApp(t, dir, true)
+	restored.loadHistory()
+
+	want := []struct {
+		text   string
+		pinned bool
+	}{{"first", false}, {"second", true}, {"third", false}}
+	if len(restored.history) != len(want) {
+		t.Fatalf("expected %d items, got %d", len(want), len(restored.history))
+	}
+	for i, w := range want {
+		got := restored.history[i]
+		if got.Text != w.text || got.Pinned != w.pinned {
+			t.Errorf("position %d: got (%q, pinned=%v), want (%q, pinned=%v)", i, got.Text, got.Pinned, w.text, w.pinned)
+		}
+		if got.ID != app.history[i].ID {
+			t.Errorf("position %d: ID changed from %q to %q", i, app.history[i].ID, got.ID)
+		}
+	}
+}
+
+// TestPersist_RichFormatsRoundTrip verifies rich representations are saved with the item.
+func TestPersist_RichFormatsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, true)
+	app.addTextItem("link", map[ClipFormat][]byte{FormatHTML: []byte(`<a href="x">link</a>`)})
+	app.flushSave()
+
+	restored := newPersistentApp(t, dir, true)
+	restored.loadHistory()
+
+	if len(restored.history) != 1 || string(restored.history[0].Formats[FormatHTML]) != `<a href="x">link</a>` {
+		t.Fatalf("expected HTML to survive a restart, got %+v", restored.history)
+	}
+}
+
+// TestPersist_DisablingDropsUnpinned verifies turning the setting off restores only pins.
+func TestPersist_DisablingDropsUnpinned(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, true)
+	app.addItem("unpinned")
+	app.addItem("pinned")
+	app.TogglePin(app.history[0].ID)
+	app.flushSave()
+
+	restored := newPersistentApp(t, dir, false)
+	restored.loadHistory()
+
+	if len(restored.history) != 1 || restored.history[0].Text != "pinned" {
+		t.Fatalf("expected only 'pinned', got %+v", restored.history)
+	}
+}
+
+// TestPersist_Debounced verifies mutations are not written until the debounce fires or a flush.
+func TestPersist_Debounced(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, true)
+	app.addItem("item")
+
+	if _, err := os.Stat(app.historyPath()); !os.IsNotExist(err) {
+		t.Fatalf("expected no file before debounce, stat err = %v", err)
+	}
+
+	app.flushSave()
+	if _, err := os.Stat(app.historyPath()); err != nil {
+		t.Fatalf("expected file after flush: %v", err)
+	}
+}
+
+// TestPersist_NoDataDir verifies an App without a data directory never touches disk.
+func TestPersist_NoDataDir(t *testing.T) {
+	app := &App{}
+	app.addItem("item")
+	app.TogglePin(app.history[0].ID)
+	app.flushSave()
+
+	if app.historyPath() != "" {
+		t.Errorf("expected no history path, got %q", app.historyPath())
+	}
+}
+
+// testImage is a small fake PNG used by the image persistence tests.
+func testImage(marker byte) []byte {
+	img := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, marker}
+	return append(img, make([]byte, 100)...)
+}
+
+// TestPersist_PinnedImageRoundTrip verifies pinned images survive a restart.
+func TestPersist_PinnedImageRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, false)
+	app.addImageItem(testImage(1))
+	app.TogglePin(app.history[0].ID)
+	app.flushSave()
+
+	restored := newPersistentApp(t, dir, false)
+	restored.loadHistory()
+
+	if len(restored.history) != 1 {
+		t.Fatalf("expected 1 restored item, got %d", len(restored.history))
+	}
+	got := restored.history[0]
+	if got.Type != TypeImage || !got.Pinned {
+		t.Errorf("expected pinned image, got type=%s pinned=%v", got.Type, got.Pinned)
+	}
+	if got.ImageData != app.history[0].ImageData {
+		t.Error("restored image data differs from the original")
+	}
+	if got.ImageHash != app.history[0].ImageHash {
+		t.Errorf("expected ImageHash %q, got %q", app.history[0].ImageHash, got.ImageHash)
+	}
+}
+
+// TestPersist_ImagesStoredAsFiles verifies history.json references images by hash.
+func TestPersist_ImagesStoredAsFiles(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, true)
+	app.addImageItem(testImage(1))
+	app.addImageItem(testImage(2))
+	app.flushSave()
+
+	data, err := os.ReadFile(app.historyPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "base64") {
+		t.Error("history.json should not inline image data")
+	}
+	files, err := os.ReadDir(app.imagesDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("expected 2 image files, got %d", len(files))
+	}
+}
+
+// TestPersist_LegacyInlineImage verifies files that inline base64 images still load.
+func TestPersist_LegacyInlineImage(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `[{"type": "image", "imageData": "data:image/png;base64,` + encodeBase64(testImage(1)) + `", "pinned": true}]`
+	if err := os.WriteFile(filepath.Join(dir, "history.json"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := newPersistentApp(t, dir, false)
+	app.loadHistory()
+
+	if len(app.history) != 1 || app.history[0].Type != TypeImage || app.history[0].ImageData == "" {
+		t.Fatalf("expected legacy pinned image to load, got %+v", app.history)
+	}
+}
+
+// TestPersist_MissingImageFileDropsItem verifies a dangling hash is skipped, not loaded empty.
+func TestPersist_MissingImageFileDropsItem(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, false)
+	app.addImageItem(testImage(1))
+	app.addItem("text")
+	app.TogglePin(app.history[0].ID)
+	app.TogglePin(app.history[1].ID)
+	app.flushSave()
+	if err := os.RemoveAll(app.imagesDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newPersistentApp(t, dir, false)
+	restored.loadHistory()
+
+	if len(restored.history) != 1 || restored.history[0].Text != "text" {
+		t.Fatalf("expected only the text item, got %+v", restored.history)
+	}
+}
+
+// TestPersist_UnpinnedImageBlobDeleted verifies unpinning an image deletes
+// its blob once the last backup pointing at it has rotated out.
+func TestPersist_UnpinnedImageBlobDeleted(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, false)
+	clk := newFakeClock()
+	app.clock = clk
+	app.addImageItem(testImage(1))
+	id := app.history[0].ID
+	app.TogglePin(id)
+	app.flushSave()
+
+	app.TogglePin(id)
+	for n := 0; n <= historyBackups; n++ {
+		clk.Advance(historyBackupInterval)
+		if files, _ := os.ReadDir(app.imagesDir()); len(files) != 1 {
+			t.Fatalf("save %d: expected the blob kept for the backups, found %d files", n, len(files))
+		}
+		app.addItem(fmt.Sprintf("clip %d", n))
+		app.TogglePin(app.history[0].ID)
+		app.flushSave()
+	}
+
+	files, _ := os.ReadDir(app.imagesDir())
+	if len(files) != 0 {
+		t.Errorf("expected image blob to be deleted, found %d files", len(files))
+	}
+}
+
+// TestPersist_LoadSweepsOrphans verifies loadHistory removes blobs no item references.
+func TestPersist_LoadSweepsOrphans(t *testing.T) {
+	dir := t.TempDir()
+	app := newPersistentApp(t, dir, false)
+	app.addImageItem(testImage(1))
+	app.TogglePin(app.history[0].ID)
+	app.flushSave()
+	orphan := filepath.Join(app.imagesDir(), hashBytes([]byte("orphan"))+".png")
+	if err := os.WriteFile(orphan, []byte("orphan"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newPersistentApp(t, dir, false)
+	restored.loadHistory()
+
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("expected orphan to be removed, stat err = %v", err)
+	}
+	if len(restored.history) != 1 || restored.history[0].Type != TypeImage {
+		t.Fatalf("expected pinned image to load, got %+v", restored.history)
+	}
+	// The restored image must dedup against a fresh capture of the same bytes
+	restored.addImageItem(testImage(1))
+	if len(restored.history) != 1 {
+		t.Errorf("expected dedup against restored image, got %d items", len(restored.history))
+	}
+}
@@ -0,0 +1,43 @@
+//go:build !darwin && !linux
+
+package main
+
+import (
+	"errors"
+
+	"golang.design/x/hotkey"
+)
+
+// errUnsupported is returned by platform hooks that have no implementation here.
+var errUnsupported = errors.New("not supported on this platform")
+
+// modifierNames maps the modifier part of a hotkey spec to this platform's modifier.
+var modifierNames = map[string]hotkey.Modifier{
+	"ctrl":    hotkey.ModCtrl,
+	"control": hotkey.ModCtrl,
+	"shift":   hotkey.ModShift,
+}
+
+// defaultHotkey shows the island when settings do not name a hotkey.
+const defaultHotkey = "ctrl+shift+v"
+
+// newSystemBackend reports that no clipboard backend exists for this platform.
+func newSystemBackend() (ClipboardBackend, error) {
+	return nil, errUnsupported
+}
+
+// cursorAndScreen has no cursor to query on this platform; it returns a
+// 1920x1080 screen with the cursor at the centre.
+func cursorAndScreen() (cx, cy, sw, sh, scale int) {
+	return 960, 540, 1920, 1080, 1
+}
+
+func frontmostApp() (string, error) { return "", errUnsupported }
+
+func activateApp(string) error { return errUnsupported }
+
+func newSystemKeyring() keyring { return nil }
+
+func checkPrivateDir(string) error { return errUnsupported }
+
+func lockInstance(string) (func(), error) { return nil, errUnsupported }
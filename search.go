@@ -0,0 +1,180 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+)
+
+// SearchFilters narrows SearchHistory results. Zero fields don't filter.
+type SearchFilters struct {
+	Types  []string  `json:"types"`  // Item types or content kinds to include, see itemIsKind
+	Pinned *bool     `json:"pinned"` // Only pinned (true) or only unpinned (false) items
+	Since  time.Time `json:"since"`  // Only items created at or after this time
+	Until  time.Time `json:"until"`  // Only items created before this time
+}
+
+// SearchResult is one matching item. Matches are the ranges of Item.Text
+// to highlight, in UTF-16 code units so the frontend can slice strings
+// directly.
+type SearchResult struct {
+	Item    ClipItem     `json:"item"`
+	Score   int          `json:"score"`
+	Matches []MatchRange `json:"matches"`
+}
+
+// MatchRange is a half-open range [Start, End) of highlighted text.
+type MatchRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Scoring for fuzzy matches. A contiguous substring always outranks a
+// scattered subsequence; within each kind, earlier and word-aligned
+// matches rank higher. In frecency mode, frequently used items get up to
+// maxFrecencyBoost on top.
+const (
+	substringScore   = 100000
+	maxPositionCost  = 1000
+	charScore        = 10
+	consecutiveBonus = 15
+	boundaryBonus    = 10
+	frecencyWeight   = 25
+	maxFrecencyBoost = 250
+	maxHighlights    = 50 // Occurrences highlighted per item
+)
+
+// SearchHistory returns the items matching query and filters, best match
+// first; equal scores keep GetHistory order. An empty query matches every
+// item that passes the filters. Exported for Wails binding.
+func (a *App) SearchHistory(query string, filters SearchFilters) []SearchResult {
+	needle := []rune(strings.ToLower(strings.TrimSpace(query)))
+	byFrecency := a.GetSettings().SortMode == SortFrecency
+	now := time.Now()
+	var results []SearchResult
+	for _, item := range a.GetHistory() {
+		if !filters.match(item) {
+			continue
+		}
+		if len(needle) == 0 {
+			results = append(results, SearchResult{Item: item})
+			continue
+		}
+		score, matches, ok := fuzzyMatch(item.Text, needle)
+		if !ok {
+			continue
+		}
+		if byFrecency {
+			score += min(int(frecency(item, now)*frecencyWeight), maxFrecencyBoost)
+		}
+		results = append(results, SearchResult{Item: item, Score: score, Matches: matches})
+	}
+	slices.SortStableFunc(results, func(x, y SearchResult) int {
+		return y.Score - x.Score
+	})
+	return results
+}
+
+// match reports whether item passes the filters.
+func (f SearchFilters) match(item ClipItem) bool {
+	if len(f.Types) > 0 && !slices.ContainsFunc(f.Types, func(t string) bool { return itemIsKind(item, t) }) {
+		return false
+	}
+	if f.Pinned != nil && item.Pinned != *f.Pinned {
+		return false
+	}
+	if !f.Since.IsZero() && item.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !item.CreatedAt.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// itemIsKind reports whether item is of the given kind: an item type
+// ("text", "image") or a ContentKind ("url", "json", ...).
+func itemIsKind(item ClipItem, kind string) bool {
+	return item.Type == ClipItemType(kind) || (item.Kind != "" && item.Kind == ContentKind(kind))
+}
+
+// fuzzyMatch matches the lowercase needle against text, first as a
+// case-insensitive substring (highlighting every occurrence), then as a
+// subsequence of characters in order.
+func fuzzyMatch(text string, needle []rune) (int, []MatchRange, bool) {
+	hay := []rune(text)
+	lower := make([]rune, len(hay))
+	for i, r := range hay {
+		lower[i] = unicode.ToLower(r)
+	}
+	offsets := utf16Offsets(hay)
+
+	if first := runeIndex(lower, needle, 0); first >= 0 {
+		score := substringScore - min(first, maxPositionCost)
+		if isBoundary(hay, first) {
+			score += boundaryBonus
+		}
+		var matches []MatchRange
+		for i := first; i >= 0 && len(matches) < maxHighlights; i = runeIndex(lower, needle, i+len(needle)) {
+			matches = append(matches, MatchRange{offsets[i], offsets[i+len(needle)]})
+		}
+		return score, matches, true
+	}
+
+	score := 0
+	var matches []MatchRange
+	next := 0
+	for _, r := range needle {
+		i := slices.Index(lower[next:], r)
+		if i < 0 {
+			return 0, nil, false
+		}
+		i += next
+		score += charScore
+		if isBoundary(hay, i) {
+			score += boundaryBonus
+		}
+		if n := len(matches); n > 0 && i == next {
+			score += consecutiveBonus
+			matches[n-1].End = offsets[i+1]
+		} else {
+			score -= min(i-next, charScore) // Gap penalty
+			matches = append(matches, MatchRange{offsets[i], offsets[i+1]})
+		}
+		next = i + 1
+	}
+	return score, matches, true
+}
+
+// runeIndex returns the index of needle in hay at or after from, or -1.
+func runeIndex(hay, needle []rune, from int) int {
+	for i := from; i+len(needle) <= len(hay); i++ {
+		if slices.Equal(hay[i:i+len(needle)], needle) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isBoundary reports whether hay[i] starts a word.
+func isBoundary(hay []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, cur := hay[i-1], hay[i]
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(cur) // camelCase
+}
+
+// utf16Offsets maps each rune index of hay (and len(hay)) to its UTF-16 offset.
+func utf16Offsets(hay []rune) []int {
+	offsets := make([]int, len(hay)+1)
+	for i, r := range hay {
+		offsets[i+1] = offsets[i] + utf16.RuneLen(r)
+	}
+	return offsets
+}
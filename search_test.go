@@ -0,0 +1,118 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSearchHistory_Substring verifies case-insensitive substring matches
+// with highlight ranges for every occurrence.
+func TestSearchHistory_Substring(t *testing.T) {
+	app := &App{}
+	app.addItem("nothing here")
+	app.addItem("Hello world, hello again")
+
+	results := app.SearchHistory("HELLO", SearchFilters{})
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	want := []MatchRange{{0, 5}, {13, 18}}
+	got := results[0].Matches
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("expected matches %v, got %v", want, got)
+	}
+}
+
+// TestSearchHistory_Fuzzy verifies subsequence matches are found but rank
+// below contiguous substrings.
+func TestSearchHistory_Fuzzy(t *testing.T) {
+	app := &App{}
+	app.addItem("git checkout main")
+	app.addItem("go build ./...")
+	app.addItem("gco")
+
+	results := app.SearchHistory("gco", SearchFilters{})
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	if results[0].Item.Text != "gco" || results[1].Item.Text != "git checkout main" {
+		t.Errorf("unexpected ranking: %q, %q", results[0].Item.Text, results[1].Item.Text)
+	}
+	want := []MatchRange{{0, 1}, {4, 5}, {9, 10}}
+	got := results[1].Matches
+	if len(got) != len(want) {
+		t.Fatalf("expected matches %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected matches %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+// TestSearchHistory_UTF16Offsets verifies offsets count UTF-16 code units,
+// as JavaScript strings do.
+func TestSearchHistory_UTF16Offsets(t *testing.T) {
+	app := &App{}
+	app.addItem("🎉 party")
+
+	results := app.SearchHistory("party", SearchFilters{})
+	if len(results) != 1 || results[0].Matches[0] != (MatchRange{3, 8}) {
+		t.Errorf("expected match [3, 8), got %+v", results)
+	}
+}
+
+// TestSearchHistory_EmptyQuery verifies an empty query returns everything
+// in history order.
+func TestSearchHistory_EmptyQuery(t *testing.T) {
+	app := &App{}
+	app.addItem("a")
+	app.addItem("b")
+
+	results := app.SearchHistory("  ", SearchFilters{})
+	if len(results) != 2 || results[0].Item.Text != "b" || results[1].Item.Text != "a" {
+		t.Errorf("expected [b a], got %+v", results)
+	}
+}
+
+// TestSearchHistory_Filters verifies type, pinned and date filters.
+func TestSearchHistory_Filters(t *testing.T) {
+	app := &App{}
+	app.addItem("https://example.com/page")
+	app.addItem("plain text")
+	app.addImageItem(testImage(1))
+	app.TogglePin(app.history[1].ID) // "plain text"
+
+	if r := app.SearchHistory("", SearchFilters{Types: []string{"url"}}); len(r) != 1 || r[0].Item.Text != "https://example.com/page" {
+		t.Errorf("url filter: got %+v", r)
+	}
+	if r := app.SearchHistory("", SearchFilters{Types: []string{string(TypeImage)}}); len(r) != 1 || r[0].Item.Type != TypeImage {
+		t.Errorf("image filter: got %+v", r)
+	}
+	pinned := true
+	if r := app.SearchHistory("", SearchFilters{Pinned: &pinned}); len(r) != 1 || r[0].Item.Text != "plain text" {
+		t.Errorf("pinned filter: got %+v", r)
+	}
+	if r := app.SearchHistory("", SearchFilters{Since: time.Now().Add(time.Hour)}); len(r) != 0 {
+		t.Errorf("since filter: expected nothing, got %+v", r)
+	}
+	if r := app.SearchHistory("", SearchFilters{Until: time.Now().Add(time.Hour)}); len(r) != 3 {
+		t.Errorf("until filter: expected all 3, got %d", len(r))
+	}
+}
+
+// TestSearchHistory_Frecency verifies often-used items rank first among
+// equally good matches in frecency mode.
+func TestSearchHistory_Frecency(t *testing.T) {
+	app := &App{}
+	app.settings.SortMode = SortFrecency
+	app.addItem("deploy staging")
+	app.addItem("deploy prod")
+	app.history[1].UseCount = 5 // "deploy staging"
+
+	results := app.SearchHistory("deploy", SearchFilters{})
+	if len(results) != 2 || results[0].Item.Text != "deploy staging" {
+		t.Errorf("expected the used item first, got %+v", results)
+	}
+}
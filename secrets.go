@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Secret kinds reported by detectSecret.
+const (
+	SecretCreditCard = "credit card"
+	SecretAWSKey     = "AWS key"
+	SecretGitHub     = "GitHub token"
+	SecretSlack      = "Slack token"
+	SecretPrivateKey = "private key"
+	SecretJWT        = "JWT"
+	SecretRandom     = "high-entropy string"
+)
+
+// secretPatterns find well-known credential formats anywhere in a clip.
+var secretPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{SecretPrivateKey, regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)},
+	{SecretAWSKey, regexp.MustCompile(`\b(AKIA|ASIA|AGPA|AIDA|AROA|ANPA)[0-9A-Z]{16}\b|(?i)aws_secret_access_key\s*[=:]\s*\S{40}`)},
+	{SecretGitHub, regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b|\bgithub_pat_[A-Za-z0-9_]{22,}\b`)},
+	{SecretSlack, regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}|https://hooks\.slack\.com/services/[A-Za-z0-9/]+`)},
+	{SecretJWT, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+}
+
+var cardPattern = regexp.MustCompile(`^\d{4}([ -]?\d{2,4}){2,4}$`)
+
+// Bounds for high-entropy detection: a single base64url token of this
+// length whose characters carry at least minEntropy bits each and mix upper
+// case, lower case and digits looks like a generated password or API key,
+// unless it splits into words of minWordLength characters on average.
+const (
+	minRandomLength = 32
+	maxRandomLength = 512
+	minEntropy      = 4.0
+	minWordLength   = 3.0
+)
+
+// tokenPattern matches the base64url alphabet, hex included, with optional
+// padding. Dots, slashes and spaces rule out paths, URLs, hosts and prose.
+var tokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+={0,2}$`)
+
+// wordPattern splits camelCase and snake_case identifiers into their words,
+// acronyms and numbers.
+var wordPattern = regexp.MustCompile(`[A-Z]?[a-z]+|[A-Z]+|[0-9]+`)
+
+// detectSecret returns the kind of secret text looks like, or "" if none.
+func detectSecret(text string) string {
+	text = strings.TrimSpace(text)
+	if isCardNumber(text) {
+		return SecretCreditCard
+	}
+	for _, p := range secretPatterns {
+		if p.pattern.MatchString(text) {
+			return p.kind
+		}
+	}
+	if isRandomToken(text) {
+		return SecretRandom
+	}
+	return ""
+}
+
+// isCardNumber reports whether text is a 13 to 19 digit number, optionally
+// grouped by spaces or dashes, that passes the Luhn check.
+func isCardNumber(text string) bool {
+	if !cardPattern.MatchString(text) {
+		return false
+	}
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, text)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range len(digits) {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// isRandomToken reports whether text is one token that looks randomly
+// generated. Identifiers such as getUserByID2024Handler are excluded: a
+// false positive masks the clip and drops it from the history.
+func isRandomToken(text string) bool {
+	if len(text) < minRandomLength || len(text) > maxRandomLength || !tokenPattern.MatchString(text) {
+		return false
+	}
+	if !strings.ContainsFunc(text, unicode.IsUpper) || !strings.ContainsFunc(text, unicode.IsLower) ||
+		!strings.ContainsFunc(text, unicode.IsDigit) {
+		return false
+	}
+	return !isIdentifier(text) && shannonEntropy(text) >= minEntropy
+}
+
+// isIdentifier reports whether text reads as words joined in camelCase or
+// snake_case. Random tokens switch case and between letters and digits so
+// often that their words rarely average three characters.
+func isIdentifier(text string) bool {
+	words := wordPattern.FindAllString(text, -1)
+	letters := 0
+	for _, word := range words {
+		letters += len(word)
+	}
+	return float64(letters) >= minWordLength*float64(len(words))
+}
+
+// shannonEntropy returns the average information per character of s, in bits.
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	entropy := 0.0
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// maskLength caps how many bullets stand in for masked text.
+const maskLength = 16
+
+// masked returns item as clients may see it: a sensitive item that is not
+// revealed has its text replaced by bullets (keeping a card's last four
+// digits) and its rich formats dropped.
+func (item ClipItem) masked() ClipItem {
+	if !item.Sensitive || item.revealed {
+		return item
+	}
+	text := strings.Repeat("•", min(utf8.RuneCountInString(item.Text), maskLength))
+	if item.SecretKind == SecretCreditCard {
+		digits := strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, item.Text)
+		text = "•••• " + digits[len(digits)-4:]
+	}
+	item.Text, item.Masked = text, true
+	item.Kind, item.Language, item.Formats = "", "", nil
+	return item
+}
+
+// maskItems masks every item in place, see ClipItem.masked.
+func maskItems(items []ClipItem) {
+	for i := range items {
+		items[i] = items[i].masked()
+	}
+}
+
+// RevealItem shows the text of a sensitive item until ConcealItem is
+// called or the item is copied again. Exported for Wails binding.
+func (a *App) RevealItem(id string) error {
+	return a.setRevealed(id, true)
+}
+
+// ConcealItem masks a revealed sensitive item again. Exported for Wails binding.
+func (a *App) ConcealItem(id string) error {
+	return a.setRevealed(id, false)
+}
+
+func (a *App) setRevealed(id string, revealed bool) error {
+	a.mu.Lock()
+	index := a.indexOf(id)
+	if index < 0 {
+		a.mu.Unlock()
+		return fmt.Errorf("unknown item %q", id)
+	}
+	if !a.history[index].Sensitive {
+		a.mu.Unlock()
+		return fmt.Errorf("item %q is not sensitive", id)
+	}
+	prevIDs := a.historyIDs()
+	a.history[index].revealed = revealed
+	change := a.historyChanged(prevIDs, a.history[index])
+	a.mu.Unlock()
+
+	a.publish(change)
+	return nil
+}
+
+// expireSensitive deletes sensitive items whose TTL ran out by now, pinned
+// or not, and reports whether it deleted any.
+func (a *App) expireSensitive(now time.Time) bool {
+	a.mu.Lock()
+	prevIDs := a.historyIDs()
+	kept := a.history[:0]
+	for _, item := range a.history {
+		if item.Sensitive && !item.ExpiresAt.IsZero() && !now.Before(item.ExpiresAt) {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	if len(kept) == len(prevIDs) {
+		a.mu.Unlock()
+		return false
+	}
+	clear(a.history[len(kept):])
+	a.history = kept
+	change := a.historyChanged(prevIDs)
+	a.mu.Unlock()
+
+	log.Printf("[clipboard] Expired %d sensitive items", len(prevIDs)-len(kept))
+	a.publish(change)
+	return true
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+// Settings holds user preferences, persisted as settings.json in the
+// clipboard-island XDG config directory. Zero fields mean "use the default",
+// see withDefaults.
+type Settings struct {
+	// Hotkey shows the island, e.g. "cmd+shift+v" or "ctrl+alt+v".
+	Hotkey string `json:"hotkey"`
+
+	// Optional action hotkeys; empty leaves them unbound.
+	PastePreviousHotkey string `json:"pastePreviousHotkey"` // Pastes the second most recent item
+	TogglePauseHotkey   string `json:"togglePauseHotkey"`   // Pauses or resumes capture
+
+	// Island window size.
+	WindowWidth  int `json:"windowWidth"`
+	WindowHeight int `json:"windowHeight"`
+
+	// Clipboard polling: PollIntervalMs while active, IdlePollIntervalMs
+	// once nothing has changed for a few seconds.
+	PollIntervalMs     int `json:"pollIntervalMs"`
+	IdlePollIntervalMs int `json:"idlePollIntervalMs"`
+
+	// ImageMaxDimension is the longest side captured images are scaled down to.
+	ImageMaxDimension int `json:"imageMaxDimension"`
+
+	// PasteMode is how SelectItem pastes text: "original", "plain" or "singleLine".
+	PasteMode PasteMode `json:"pasteMode"`
+
+	// SortMode orders the history: SortRecent or SortFrecency.
+	SortMode string `json:"sortMode"`
+
+	// Capture rules, see excludeReason. ExcludedApps are bundle IDs on
+	// macOS and WM_CLASS names on X11, matched case-insensitively; text
+	// matching any of ExcludePatterns (Go regular expressions) is skipped.
+	ExcludedApps    []string `json:"excludedApps"`
+	ExcludePatterns []string `json:"excludePatterns"`
+
+	// SensitiveTTLSeconds is how long clips that look like secrets stay in
+	// the history, pinned or not.
+	SensitiveTTLSeconds int `json:"sensitiveTtlSeconds"`
+
+	// PersistHistory saves the whole history to disk, not only pinned items.
+	PersistHistory bool `json:"persistHistory"`
+
+	// PersistPause keeps capture paused across restarts, see PauseCapture.
+	PersistPause bool `json:"persistPause"`
+
+	// EncryptHistory encrypts the history file and images on disk, see cryptor.
+	EncryptHistory bool `json:"encryptHistory"`
+
+	// History limits, enforced together by trimToCap. Pinned items are never
+	// evicted but count toward each limit.
+	MaxItems      int `json:"maxItems"`      // Total items
+	MaxImages     int `json:"maxImages"`     // Image items
+	MaxTotalBytes int `json:"maxTotalBytes"` // Text plus encoded image bytes
+	MaxTextLength int `json:"maxTextLength"` // Longer text clips are not captured
+}
+
+// EventSettingsChanged is emitted with the new Settings whenever they are applied.
+const EventSettingsChanged = "settings:changed"
+
+// settingsPollInterval is how often watchSettings checks the file for external edits.
+const settingsPollInterval = 2 * time.Second
+
+// defaultSettings returns the settings used when no config file exists.
+func defaultSettings() Settings {
+	return Settings{
+		Hotkey:              defaultHotkey,
+		WindowWidth:         380,
+		WindowHeight:        370,
+		PollIntervalMs:      200,
+		IdlePollIntervalMs:  1000,
+		ImageMaxDimension:   1200,
+		PasteMode:           PasteOriginal,
+		SortMode:            SortRecent,
+		SensitiveTTLSeconds: 120,
+		MaxItems:            30,
+		MaxImages:           30,
+		MaxTotalBytes:       256 << 20,
+		MaxTextLength:       1 << 20,
+	}
+}
+
+// withDefaults returns s with every unset (zero) field replaced by its default.
+func (s Settings) withDefaults() Settings {
+	d := defaultSettings()
+	if s.Hotkey == "" {
+		s.Hotkey = d.Hotkey
+	}
+	if s.PasteMode == "" {
+		s.PasteMode = d.PasteMode
+	}
+	if s.SortMode == "" {
+		s.SortMode = d.SortMode
+	}
+	setDefault(&s.WindowWidth, d.WindowWidth)
+	setDefault(&s.WindowHeight, d.WindowHeight)
+	setDefault(&s.PollIntervalMs, d.PollIntervalMs)
+	setDefault(&s.IdlePollIntervalMs, d.IdlePollIntervalMs)
+	setDefault(&s.ImageMaxDimension, d.ImageMaxDimension)
+	setDefault(&s.SensitiveTTLSeconds, d.SensitiveTTLSeconds)
+	setDefault(&s.MaxItems, d.MaxItems)
+	setDefault(&s.MaxImages, d.MaxImages)
+	setDefault(&s.MaxTotalBytes, d.MaxTotalBytes)
+	setDefault(&s.MaxTextLength, d.MaxTextLength)
+	return s
+}
+
+func setDefault(field *int, def int) {
+	if *field == 0 {
+		*field = def
+	}
+}
+
+// validate reports the first setting that is out of range. It expects
+// settings that already went through withDefaults.
+func (s Settings) validate() error {
+	if err := validateHotkeys(s.Hotkey, s.PastePreviousHotkey, s.TogglePauseHotkey); err != nil {
+		return err
+	}
+	if err := s.PasteMode.validate(); err != nil {
+		return err
+	}
+	if s.SortMode != SortRecent && s.SortMode != SortFrecency {
+		return fmt.Errorf("sortMode must be %q or %q, got %q", SortRecent, SortFrecency, s.SortMode)
+	}
+	if _, err := compilePatterns(s.ExcludePatterns); err != nil {
+		return err
+	}
+	checks := []struct {
+		name     string
+		value    int
+		min, max int
+	}{
+		{"windowWidth", s.WindowWidth, 200, 1200},
+		{"windowHeight", s.WindowHeight, minWindowHeight, maxWindowHeight},
+		{"pollIntervalMs", s.PollIntervalMs, 50, 5000},
+		{"idlePollIntervalMs", s.IdlePollIntervalMs, s.PollIntervalMs, 60000},
+		{"imageMaxDimension", s.ImageMaxDimension, 100, 10000},
+		{"sensitiveTtlSeconds", s.SensitiveTTLSeconds, 5, 86400},
+		{"maxItems", s.MaxItems, 1, 100000},
+		{"maxImages", s.MaxImages, 1, 100000},
+		{"maxTotalBytes", s.MaxTotalBytes, 1 << 20, 1 << 34},
+		{"maxTextLength", s.MaxTextLength, 1, 64 << 20},
+	}
+	for _, c := range checks {
+		if c.value < c.min || c.value > c.max {
+			return fmt.Errorf("%s must be between %d and %d, got %d", c.name, c.min, c.max, c.value)
+		}
+	}
+	return nil
+}
+
+// validateHotkeys checks that each non-empty spec parses and that no two
+// specs name the same key combination.
+func validateHotkeys(specs ...string) error {
+	seen := make(map[string]string)
+	for _, spec := range specs {
+		if spec == "" {
+			continue
+		}
+		mods, key, err := parseHotkey(spec)
+		if err != nil {
+			return err
+		}
+		id := hotkeyKey(mods, key)
+		if other, ok := seen[id]; ok {
+			return fmt.Errorf("hotkey %q conflicts with %q", spec, other)
+		}
+		seen[id] = spec
+	}
+	return nil
+}
+
+// getSettingsFilePath returns the path to the settings file.
+func getSettingsFilePath() string {
+	return filepath.Join(xdg.ConfigHome, "clipboard-island", "settings.json")
+}
+
+// readSettings parses and validates the settings file. A missing file
+// yields the defaults.
+func readSettings(path string) (Settings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return defaultSettings(), nil
+		}
+		return Settings{}, err
+	}
+	var settings Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return Settings{}, err
+	}
+	settings = settings.withDefaults()
+	if err := settings.validate(); err != nil {
+		return Settings{}, err
+	}
+	return settings, nil
+}
+
+// loadSettings reads settings from path, falling back to defaults if the
+// file is unreadable or invalid.
+func loadSettings(path string) Settings {
+	settings, err := readSettings(path)
+	if err != nil {
+		log.Printf("[clipboard] invalid settings file, using defaults: %v", err)
+		return defaultSettings()
+	}
+	return settings
+}
+
+// writeSettings saves settings as indented JSON, creating the directory if needed.
+func writeSettings(path string, settings Settings) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// GetSettings returns the current settings with defaults filled in.
+// Exported for Wails binding.
+func (a *App) GetSettings() Settings {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.settings.withDefaults()
+}
+
+// UpdateSettings validates, saves and applies new settings. Zero fields
+// take their defaults. Exported for Wails binding.
+func (a *App) UpdateSettings(settings Settings) error {
+	settings = settings.withDefaults()
+	if err := settings.validate(); err != nil {
+		return err
+	}
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	if a.settingsPath != "" {
+		if err := writeSettings(a.settingsPath, settings); err != nil {
+			return fmt.Errorf("save settings: %w", err)
+		}
+		a.noteSettingsFile()
+	}
+	a.applySettings(settings)
+	return nil
+}
+
+// applySettings makes settings current and pushes every change out to the
+// history limits, persistence, hotkey and window.
+func (a *App) applySettings(settings Settings) {
+	excludes := compileExcludes(settings.ExcludePatterns)
+	a.mu.Lock()
+	prev := a.settings.withDefaults()
+	a.settings = settings
+	a.excludes = excludes
+	prevIDs := a.historyIDs()
+	a.capHistory()
+	var change *HistoryChange
+	if len(a.history) != len(prevIDs) || prev.SortMode != settings.SortMode {
+		c := a.historyChanged(prevIDs)
+		change = &c
+	}
+	a.mu.Unlock()
+
+	if change != nil {
+		a.publish(*change)
+	}
+	if change != nil || prev.PersistHistory != settings.PersistHistory || prev.EncryptHistory != settings.EncryptHistory {
+		a.scheduleSave()
+	}
+	if prev.Hotkey != settings.Hotkey || prev.PastePreviousHotkey != settings.PastePreviousHotkey ||
+		prev.TogglePauseHotkey != settings.TogglePauseHotkey {
+		a.applyHotkeys(settings) // Failures are logged and reported through EventHotkeysChanged
+	}
+	if prev.PersistPause != settings.PersistPause {
+		a.savePauseState()
+	}
+	if prev.WindowWidth != settings.WindowWidth || prev.WindowHeight != settings.WindowHeight {
+		a.resizeWindow(settings)
+	}
+	if a.emit != nil {
+		a.emit(EventSettingsChanged, settings)
+	}
+}
+
+// noteSettingsFile records the settings file's current modification time,
+// so watchSettings does not mistake our own write for an external edit.
+// Caller must hold a.settingsMu.
+func (a *App) noteSettingsFile() {
+	info, err := os.Stat(a.settingsPath)
+	if err != nil {
+		a.settingsModTime = time.Time{}
+		return
+	}
+	a.settingsModTime = info.ModTime()
+}
+
+// watchSettings polls the settings file and applies external edits until
+// ctx is cancelled. Invalid edits are logged and ignored, keeping the
+// current settings.
+func (a *App) watchSettings(ctx context.Context) {
+	a.settingsMu.Lock()
+	a.noteSettingsFile()
+	a.settingsMu.Unlock()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(settingsPollInterval):
+		}
+		a.reloadSettingsIfChanged()
+	}
+}
+
+// reloadSettingsIfChanged applies the settings file if it changed since it
+// was last read or written by us.
+func (a *App) reloadSettingsIfChanged() {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	info, err := os.Stat(a.settingsPath)
+	var modTime time.Time
+	if err == nil {
+		modTime = info.ModTime()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("[clipboard] failed to stat settings file: %v", err)
+		return
+	}
+
+	if modTime.Equal(a.settingsModTime) {
+		return
+	}
+	a.settingsModTime = modTime
+
+	settings, err := readSettings(a.settingsPath)
+	if err != nil {
+		log.Printf("[clipboard] ignoring edited settings file: %v", err)
+		return
+	}
+	log.Println("[clipboard] Settings file changed, applying")
+	a.applySettings(settings)
+}
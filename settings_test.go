@@ -0,0 +1,177 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestLoadSettings_Missing verifies defaults are used when no file exists.
+func TestLoadSettings_Missing(t *testing.T) {
+	got := loadSettings(filepath.Join(t.TempDir(), "settings.json"))
+	if !reflect.DeepEqual(got, defaultSettings()) {
+		t.Errorf("expected defaults, got %+v", got)
+	}
+}
+
+// TestLoadSettings_File verifies fields are read from the file.
+func TestLoadSettings_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(path, []byte(`{"persistHistory": true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadSettings(path); !got.PersistHistory {
+		t.Errorf("expected persistHistory to be true, got %+v", got)
+	}
+}
+
+// TestLoadSettings_Malformed verifies a broken file falls back to defaults.
+func TestLoadSettings_Malformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(path, []byte(`{"persistHistory": tru`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadSettings(path); !reflect.DeepEqual(got, defaultSettings()) {
+		t.Errorf("expected defaults, got %+v", got)
+	}
+}
+
+// TestLoadSettings_Invalid verifies out-of-range values fall back to defaults.
+func TestLoadSettings_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(path, []byte(`{"maxItems": -5}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadSettings(path); !reflect.DeepEqual(got, defaultSettings()) {
+		t.Errorf("expected defaults, got %+v", got)
+	}
+}
+
+// TestSettingsValidate verifies range and hotkey checks.
+func TestSettingsValidate(t *testing.T) {
+	if err := defaultSettings().validate(); err != nil {
+		t.Fatalf("defaults should be valid: %v", err)
+	}
+	tests := []struct {
+		name   string
+		modify func(*Settings)
+	}{
+		{"bad hotkey", func(s *Settings) { s.Hotkey = "v" }},
+		{"narrow window", func(s *Settings) { s.WindowWidth = 50 }},
+		{"tall window", func(s *Settings) { s.WindowHeight = 5000 }},
+		{"fast poll", func(s *Settings) { s.PollIntervalMs = 1 }},
+		{"idle faster than active", func(s *Settings) { s.IdlePollIntervalMs = 100 }},
+		{"negative items", func(s *Settings) { s.MaxItems = -1 }},
+		{"bad exclude pattern", func(s *Settings) { s.ExcludePatterns = []string{"[a-"} }},
+	}
+	for _, tt := range tests {
+		s := defaultSettings()
+		tt.modify(&s)
+		if err := s.validate(); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+// TestUpdateSettings_AppliesAndSaves verifies new limits are enforced,
+// pushed to the frontend and written to the settings file.
+func TestUpdateSettings_AppliesAndSaves(t *testing.T) {
+	app := &App{settingsPath: filepath.Join(t.TempDir(), "settings.json")}
+	for i := 0; i < 5; i++ {
+		app.addItem("item" + string(rune('a'+i)))
+	}
+	events := recordEvents(app)
+
+	settings := app.GetSettings()
+	settings.MaxItems = 3
+	if err := app.UpdateSettings(settings); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+
+	if len(app.GetHistory()) != 3 {
+		t.Errorf("expected history capped to 3, got %d", len(app.GetHistory()))
+	}
+	if changes := events(); len(changes) != 1 || len(changes[0].Removed) != 2 {
+		t.Errorf("expected one change removing 2 items, got %+v", changes)
+	}
+	if got := loadSettings(app.settingsPath); got.MaxItems != 3 {
+		t.Errorf("expected maxItems 3 in file, got %d", got.MaxItems)
+	}
+}
+
+// TestUpdateSettings_RejectsInvalid verifies invalid settings change nothing.
+func TestUpdateSettings_RejectsInvalid(t *testing.T) {
+	app := &App{settingsPath: filepath.Join(t.TempDir(), "settings.json")}
+	settings := app.GetSettings()
+	settings.Hotkey = "ctrl+nosuchkey"
+	if err := app.UpdateSettings(settings); err == nil {
+		t.Fatal("expected error for invalid hotkey")
+	}
+	if !reflect.DeepEqual(app.GetSettings(), defaultSettings()) {
+		t.Errorf("settings changed after rejected update: %+v", app.GetSettings())
+	}
+	if _, err := os.Stat(app.settingsPath); !os.IsNotExist(err) {
+		t.Errorf("expected no settings file, got %v", err)
+	}
+}
+
+// TestReloadSettings_ExternalEdit verifies edits to the file are applied,
+// and invalid edits are ignored.
+func TestReloadSettings_ExternalEdit(t *testing.T) {
+	app := &App{settingsPath: filepath.Join(t.TempDir(), "settings.json")}
+	app.noteSettingsFile()
+
+	if err := os.WriteFile(app.settingsPath, []byte(`{"maxImages": 7}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app.reloadSettingsIfChanged()
+	if got := app.GetSettings().MaxImages; got != 7 {
+		t.Fatalf("expected maxImages 7 after edit, got %d", got)
+	}
+
+	if err := os.WriteFile(app.settingsPath, []byte(`{"maxImages": 0, "pollIntervalMs": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Force a different modification time, in case the filesystem's is coarse
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(app.settingsPath, future, future); err != nil {
+		t.Fatal(err)
+	}
+	app.reloadSettingsIfChanged()
+	if got := app.GetSettings().MaxImages; got != 7 {
+		t.Errorf("expected invalid edit to be ignored, got maxImages %d", got)
+	}
+}
+
+// TestUpdateSettings_ConcurrentWithReload verifies updates racing each other
+// and the file watcher leave the applied settings matching the file.
+func TestUpdateSettings_ConcurrentWithReload(t *testing.T) {
+	app := &App{settingsPath: filepath.Join(t.TempDir(), "settings.json")}
+	app.noteSettingsFile()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			settings := app.GetSettings()
+			settings.MaxItems = 10 + i
+			if err := app.UpdateSettings(settings); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			app.reloadSettingsIfChanged()
+		}()
+	}
+	wg.Wait()
+
+	app.reloadSettingsIfChanged()
+	if got, want := app.GetSettings().MaxItems, loadSettings(app.settingsPath).MaxItems; got != want {
+		t.Errorf("applied maxItems %d, file has %d", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout bounds how long shutdown waits for the background workers.
+const shutdownTimeout = 2 * time.Second
+
+// runBackground starts the long-running workers, such as watchClipboard and
+// watchSettings. They run until shutdown cancels their context.
+func (a *App) runBackground(workers ...func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopBackground = cancel
+	for _, work := range workers {
+		a.background.Add(1)
+		go func() {
+			defer a.background.Done()
+			work(ctx)
+		}()
+	}
+}
+
+// shutdown stops the app in order: it stops the workers so nothing new is
+// captured, unregisters the hotkeys, writes pending history to disk, and
+// only then releases the single-instance socket, so a new instance never
+// loads history this one has yet to write. Only the first call does
+// anything; later ones return at once.
+func (a *App) shutdown() {
+	a.shutdownOnce.Do(func() {
+		log.Println("[clipboard] Shutting down...")
+		a.stopping.Store(true)
+		if a.stopBackground != nil {
+			a.stopBackground()
+			done := make(chan struct{})
+			go func() {
+				a.background.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(shutdownTimeout):
+				log.Println("[clipboard] background workers did not stop in time")
+			}
+		}
+		a.stopHotkeys()
+		a.flushSave()
+		a.releaseInstance()
+		log.Println("[clipboard] Shutdown complete")
+	})
+}
+
+// handleSignals shuts down on SIGINT or SIGTERM and then calls quit. A
+// second signal exits at once, for a shutdown that hangs.
+func (a *App) handleSignals(quit func()) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("[clipboard] Received %v", sig)
+		go func() {
+			<-signals
+			log.Println("[clipboard] Received a second signal, exiting without cleanup")
+			os.Exit(1)
+		}()
+		a.shutdown()
+		quit()
+	}()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestShutdown_FlushesPendingSave verifies shutdown writes a pin still
+// waiting for its debounced save, and stops the watcher first.
+func TestShutdown_FlushesPendingSave(t *testing.T) {
+	dir := t.TempDir()
+	clip := newFakeBackend()
+	app := NewApp(clip)
+	app.dataDir = dir
+	app.runBackground(app.watchClipboard)
+
+	app.addItem("keep me")
+	app.TogglePin(app.GetHistory()[0].ID)
+
+	app.shutdown()
+	if _, err := os.Stat(app.historyPath()); err != nil {
+		t.Fatalf("expected the pending save to be flushed: %v", err)
+	}
+	restored := newPersistentApp(t, dir, false)
+	restored.loadHistory()
+	if len(restored.history) != 1 || restored.history[0].Text != "keep me" || !restored.history[0].Pinned {
+		t.Fatalf("expected the pinned item on disk, got %+v", restored.history)
+	}
+
+	clip.copyText("after shutdown")
+	time.Sleep(50 * time.Millisecond)
+	if n := len(app.GetHistory()); n != 1 {
+		t.Errorf("watcher still captured after shutdown: %d items", n)
+	}
+	app.shutdown() // A second call must not block or write again
+}
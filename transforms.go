@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Transform is a named text transformation that can be applied on paste.
+type Transform struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+
+	apply func(string) (string, error)
+}
+
+// transforms is the registry, in the order the UI lists them.
+var transforms = []Transform{
+	{"upper", "UPPER CASE", infallible(strings.ToUpper)},
+	{"lower", "lower case", infallible(strings.ToLower)},
+	{"title", "Title Case", infallible(titleCase)},
+	{"snake", "snake_case", infallible(snakeCase)},
+	{"camel", "camelCase", infallible(camelCase)},
+	{"trim", "Trim", infallible(strings.TrimSpace)},
+	{"collapseSpace", "Collapse whitespace", infallible(collapseSpace)},
+	{"jsonPretty", "Pretty-print JSON", jsonPretty},
+	{"jsonMinify", "Minify JSON", jsonMinify},
+	{"urlEncode", "URL encode", infallible(url.QueryEscape)},
+	{"urlDecode", "URL decode", url.QueryUnescape},
+	{"base64Encode", "Base64 encode", infallible(func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) })},
+	{"base64Decode", "Base64 decode", base64Decode},
+	{"sortLines", "Sort lines", infallible(sortLines)},
+	{"dedupeLines", "Remove duplicate lines", infallible(dedupeLines)},
+	{"shellEscape", "Escape for shell", infallible(shellEscape)},
+	{"jsonEscape", "Escape for JSON string", infallible(jsonEscape)},
+}
+
+// infallible adapts a transform that cannot fail.
+func infallible(f func(string) string) func(string) (string, error) {
+	return func(s string) (string, error) { return f(s), nil }
+}
+
+// ListTransforms returns the available transforms for PasteTransformed.
+// Exported for Wails binding.
+func (a *App) ListTransforms() []Transform {
+	return slices.Clone(transforms)
+}
+
+// PasteTransformed pastes the text item with the given ID after running it
+// through the transforms named by transformIDs, in order. Rich formats are
+// dropped since they no longer match the text. Exported for Wails binding.
+func (a *App) PasteTransformed(id string, transformIDs []string) error {
+	if len(transformIDs) == 0 {
+		return errors.New("no transforms given")
+	}
+	for _, tid := range transformIDs {
+		if findTransform(tid) == nil {
+			return fmt.Errorf("unknown transform %q", tid)
+		}
+	}
+	return a.pasteItem(id, PastePlain, transformIDs)
+}
+
+func findTransform(id string) *Transform {
+	for i := range transforms {
+		if transforms[i].ID == id {
+			return &transforms[i]
+		}
+	}
+	return nil
+}
+
+// applyTransforms runs text through the named transforms in order.
+func applyTransforms(text string, ids []string) (string, error) {
+	for _, id := range ids {
+		t := findTransform(id)
+		if t == nil {
+			return "", fmt.Errorf("unknown transform %q", id)
+		}
+		var err error
+		if text, err = t.apply(text); err != nil {
+			return "", fmt.Errorf("%s: %w", t.Label, err)
+		}
+	}
+	return text, nil
+}
+
+// splitWords breaks s into words at non-alphanumeric characters and at
+// case changes, so "parseHTTPRequest id" gives parse, HTTP, Request, id.
+func splitWords(s string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(s)
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) {
+			prev := word[len(word)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+// capitalize upper-cases the first letter of word and lower-cases the rest.
+func capitalize(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	return string(unicode.ToUpper(r)) + strings.ToLower(word[size:])
+}
+
+// titleCase capitalizes every whitespace-separated word, keeping the spacing.
+func titleCase(s string) string {
+	runes := []rune(s)
+	start := true
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			start = true
+			continue
+		}
+		if start {
+			runes[i] = unicode.ToUpper(r)
+		} else {
+			runes[i] = unicode.ToLower(r)
+		}
+		start = false
+	}
+	return string(runes)
+}
+
+func snakeCase(s string) string {
+	words := splitWords(s)
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	return strings.Join(words, "_")
+}
+
+func camelCase(s string) string {
+	words := splitWords(s)
+	for i, w := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(w)
+		} else {
+			words[i] = capitalize(w)
+		}
+	}
+	return strings.Join(words, "")
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func jsonPretty(s string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func jsonMinify(s string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(s)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// base64Decode accepts standard and URL-safe alphabets, padded or not, and
+// insists the result is text.
+func base64Decode(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	var data []byte
+	var err error
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err = enc.DecodeString(s); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", errors.New("decoded data is not text")
+	}
+	return string(data), nil
+}
+
+// splitLines splits s into lines, dropping one trailing newline.
+func splitLines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func sortLines(s string) string {
+	lines := splitLines(s)
+	slices.Sort(lines)
+	return strings.Join(lines, "\n")
+}
+
+// dedupeLines keeps the first occurrence of every line.
+func dedupeLines(s string) string {
+	seen := make(map[string]bool)
+	var lines []string
+	for _, line := range splitLines(s) {
+		if !seen[line] {
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// shellEscape quotes s as a single POSIX shell word.
+func shellEscape(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// jsonEscape escapes s for use inside a JSON string literal, without the
+// surrounding quotes.
+func jsonEscape(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s) // Encoding a string cannot fail
+	out := strings.TrimSuffix(buf.String(), "\n")
+	return out[1 : len(out)-1]
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTransforms verifies every registered transform on a sample input.
+func TestTransforms(t *testing.T) {
+	tests := []struct {
+		id, in, want string
+	}{
+		{"upper", "Hello", "HELLO"},
+		{"lower", "Hello", "hello"},
+		{"title", "hello  wORLD", "Hello  World"},
+		{"snake", "parseHTTPRequest id", "parse_http_request_id"},
+		{"camel", "user-account_ID", "userAccountId"},
+		{"trim", "  x \n", "x"},
+		{"collapseSpace", " a \n\t b ", "a b"},
+		{"jsonPretty", `{"a":[1,2]}`, "{\n  \"a\": [\n    1,\n    2\n  ]\n}"},
+		{"jsonMinify", "{ \"a\" : [ 1, 2 ] }", `{"a":[1,2]}`},
+		{"urlEncode", "a b&c", "a+b%26c"},
+		{"urlDecode", "a+b%26c", "a b&c"},
+		{"base64Encode", "hi?", "aGk/"},
+		{"base64Decode", "aGk_", "hi?"},
+		{"sortLines", "b\na\nc\n", "a\nb\nc"},
+		{"dedupeLines", "b\na\nb\na", "b\na"},
+		{"shellEscape", "it's", `'it'\''s'`},
+		{"jsonEscape", "say \"hi\"\n<b>", `say \"hi\"\n<b>`},
+	}
+	if len(tests) != len(transforms) {
+		t.Errorf("%d transforms registered but %d tested", len(transforms), len(tests))
+	}
+	for _, tt := range tests {
+		got, err := applyTransforms(tt.in, []string{tt.id})
+		if err != nil || got != tt.want {
+			t.Errorf("%s(%q) = %q, %v; want %q", tt.id, tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// TestTransforms_Errors verifies invalid input fails instead of pasting garbage.
+func TestTransforms_Errors(t *testing.T) {
+	for _, tt := range []struct{ id, in string }{
+		{"jsonPretty", "{nope"},
+		{"urlDecode", "%zz"},
+		{"base64Decode", "not base64!"},
+		{"base64Decode", "//79"}, // Decodes to invalid UTF-8
+	} {
+		if _, err := applyTransforms(tt.in, []string{tt.id}); err == nil {
+			t.Errorf("%s(%q): expected error", tt.id, tt.in)
+		}
+	}
+}
+
+// TestPasteTransformed verifies a chain is applied in order and pasted as
+// plain text.
+func TestPasteTransformed(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	app.addTextItem("Hello World", map[ClipFormat][]byte{FormatHTML: []byte("<i>Hello World</i>")})
+
+	if err := app.PasteTransformed(app.history[0].ID, []string{"snake", "upper"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-fake.pasted:
+		if string(got) != "HELLO_WORLD" {
+			t.Errorf("expected HELLO_WORLD, got %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("paste was never simulated")
+	}
+	if fake.Read(FormatHTML) != nil {
+		t.Error("rich formats should be dropped after transforming")
+	}
+}
+
+// TestPasteTransformed_Rejects verifies unknown transforms, failing
+// transforms and images leave the clipboard alone.
+func TestPasteTransformed_Rejects(t *testing.T) {
+	fake := newFakeBackend()
+	app := NewApp(fake)
+	app.addImageItem(testImage(1))
+	app.addItem("not json")
+
+	if err := app.PasteTransformed(app.history[0].ID, []string{"nope"}); err == nil {
+		t.Error("expected error for unknown transform")
+	}
+	if err := app.PasteTransformed(app.history[0].ID, []string{"jsonPretty"}); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+	if err := app.PasteTransformed(app.history[1].ID, []string{"upper"}); err == nil {
+		t.Error("expected error for image item")
+	}
+	if fake.ChangeCount() != 0 {
+		t.Error("clipboard was written despite the errors")
+	}
+}
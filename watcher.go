@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// idleAfter is how long the clipboard must stay unchanged before a polling
+// watcher slows down from Settings.PollIntervalMs to IdlePollIntervalMs.
+const idleAfter = 5 * time.Second
+
+// clock is the time source of the watcher, of timed pauses and of backup
+// rotation; tests substitute one they advance by hand.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the real clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// watchClock returns the clock the watcher, pauses and backups run on.
+func (a *App) watchClock() clock {
+	if a.clock == nil {
+		return systemClock{}
+	}
+	return a.clock
+}
+
+// watchState is the per-loop state of watchClipboard.
+type watchState struct {
+	lastCount     int
+	lastText      string
+	lastImageHash string
+}
+
+// watchClipboard captures clipboard changes until ctx is cancelled. It wakes
+// on the backend's change notifications if it has them (see ChangeNotifier)
+// and polls otherwise: every Settings.PollIntervalMs while the clipboard is
+// busy, every IdlePollIntervalMs once it has been quiet for idleAfter.
+// Every wake-up also expires sensitive items.
+func (a *App) watchClipboard(ctx context.Context) {
+	clk := a.watchClock()
+	var changes <-chan struct{} // nil, and so never ready, when polling
+	if notifier, ok := a.clip.(ChangeNotifier); ok {
+		changes = notifier.Changes()
+	}
+	log.Printf("[clipboard] Starting clipboard watcher (notifications: %v)", changes != nil)
+
+	state := &watchState{lastCount: a.clip.ChangeCount()}
+	lastChange := clk.Now()
+	for {
+		delay := pollDelay(a.GetSettings(), changes != nil, clk.Now().Sub(lastChange))
+		select {
+		case <-ctx.Done():
+			log.Println("[clipboard] Clipboard watcher stopped")
+			return
+		case <-changes:
+		case <-clk.After(delay):
+		}
+
+		now := clk.Now()
+		a.expireSensitive(now)
+		if a.pollClipboard(state) {
+			lastChange = now
+		}
+	}
+}
+
+// pollDelay returns how long the watcher waits before checking again after
+// the clipboard has been quiet for quiet. With change notifications the
+// wait only paces expiry and a safety re-check, so it is always the idle one.
+func pollDelay(settings Settings, notified bool, quiet time.Duration) time.Duration {
+	if !notified && quiet < idleAfter {
+		return time.Duration(settings.PollIntervalMs) * time.Millisecond
+	}
+	return time.Duration(settings.IdlePollIntervalMs) * time.Millisecond
+}
+
+// writeClipboard writes data to the clipboard and records the change count
+// it produced, so pollClipboard skips exactly that change and no other.
+func (a *App) writeClipboard(data map[ClipFormat][]byte) error {
+	a.clipWriteMu.Lock()
+	defer a.clipWriteMu.Unlock()
+	count, err := a.clip.Write(data)
+	if err != nil {
+		return err
+	}
+	a.ownCount = count
+	return nil
+}
+
+// pollClipboard checks the backend once and captures any new content.
+// It reports whether the clipboard changed since the previous poll.
+func (a *App) pollClipboard(state *watchState) bool {
+	// A write in progress holds clipWriteMu until ownCount is set, so the
+	// count read here is never one of ours that is not yet recorded.
+	a.clipWriteMu.Lock()
+	currentCount := a.clip.ChangeCount()
+	own := currentCount == a.ownCount
+	a.clipWriteMu.Unlock()
+
+	if currentCount == state.lastCount {
+		return false
+	}
+	state.lastCount = currentCount
+	if own {
+		return true // Our own paste
+	}
+
+	a.mu.Lock()
+	paused := a.pausedAt(a.watchClock().Now())
+	excludes := a.excludes
+	a.mu.Unlock()
+	if paused {
+		return true
+	}
+	settings := a.GetSettings()
+	if reason := a.excludeReason(settings); reason != "" {
+		log.Printf("[clipboard] Skipped clip: %s", reason)
+		return true
+	}
+
+	// Rich formats go with the text; a file list wins over the file icon
+	// some file managers offer as an image.
+	formats := make(map[ClipFormat][]byte)
+	for _, format := range richFormats {
+		if data := a.clip.Read(format); len(data) > 0 {
+			formats[format] = data
+		}
+	}
+
+	// Try reading image first
+	imgData := a.clip.Read(FormatImage)
+	if len(imgData) > 0 && formats[FormatFiles] == nil {
+		// Simple hash check for duplicates
+		hash := hashBytes(imgData)
+		if hash != state.lastImageHash {
+			state.lastImageHash = hash
+			a.addImageItem(imgData)
+			log.Printf("[clipboard] Captured image (%d bytes)", len(imgData))
+		}
+		return true
+	}
+
+	// No image, try text
+	text := string(a.clip.Read(FormatText))
+	if text == "" {
+		text = filePaths(formats[FormatFiles]) // Some file managers offer only the file list
+	}
+	if text == "" {
+		return true
+	}
+	if reason := excludedText(excludes, text); reason != "" {
+		log.Printf("[clipboard] Skipped %d chars: %s", len(text), reason)
+		return true
+	}
+
+	// Skip if same as last captured text (prevents duplicates from rapid polling)
+	if text == state.lastText {
+		return true
+	}
+	state.lastText = text
+
+	a.addTextItem(text, formats)
+	log.Printf("[clipboard] Captured %d chars", len(text))
+	return true
+}
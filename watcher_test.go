@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when advanced. Every After call is
+// reported on waits, so tests know when the watcher is idle.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+	waits  chan time.Duration
+}
+
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:   time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+		waits: make(chan time.Duration, 64),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, fakeTimer{c.now.Add(d), ch})
+	c.mu.Unlock()
+	c.waits <- d
+	return ch
+}
+
+// Advance moves the clock forward and fires the timers that came due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+		} else {
+			timer.ch <- c.now
+		}
+	}
+	c.timers = pending
+}
+
+// nextWait returns the delay of the watcher's next wait.
+func (c *fakeClock) nextWait(t *testing.T) time.Duration {
+	t.Helper()
+	select {
+	case d := <-c.waits:
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatal("watcher never waited")
+		return 0
+	}
+}
+
+// startWatcher runs app.watchClipboard on clk until the test ends, and
+// checks that cancelling its context stops it.
+func startWatcher(t *testing.T, app *App, clk *fakeClock) {
+	app.clock = clk
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		app.watchClipboard(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Error("watcher did not stop on cancel")
+		}
+	})
+}
+
+// TestWatchClipboard_PollsAdaptively verifies a polling watcher captures on
+// the active interval and slows to the idle one after idleAfter of quiet.
+func TestWatchClipboard_PollsAdaptively(t *testing.T) {
+	clip := newFakeBackend()
+	app := NewApp(pollingBackend{clip})
+	clk := newFakeClock()
+	startWatcher(t, app, clk)
+	settings := app.GetSettings()
+	active := time.Duration(settings.PollIntervalMs) * time.Millisecond
+	idle := time.Duration(settings.IdlePollIntervalMs) * time.Millisecond
+
+	if d := clk.nextWait(t); d != active {
+		t.Fatalf("expected first wait %v, got %v", active, d)
+	}
+	clip.copyText("hello")
+	clk.Advance(active)
+	clk.nextWait(t)
+	if h := app.GetHistory(); len(h) != 1 || h[0].Text != "hello" {
+		t.Fatalf("expected 'hello' captured, got %+v", h)
+	}
+
+	var quiet time.Duration
+	for d := active; d == active; d = clk.nextWait(t) {
+		clk.Advance(d)
+		quiet += d
+		if quiet > 2*idleAfter {
+			t.Fatal("watcher never went idle")
+		}
+	}
+	if quiet < idleAfter {
+		t.Errorf("went idle after %v, want at least %v", quiet, idleAfter)
+	}
+
+	clip.copyText("wake")
+	clk.Advance(idle)
+	if d := clk.nextWait(t); d != active {
+		t.Errorf("expected active polling after a change, got %v", d)
+	}
+}
+
+// TestWatchClipboard_Notifications verifies a notifying backend is captured
+// without waiting for the clock.
+func TestWatchClipboard_Notifications(t *testing.T) {
+	clip := newFakeBackend()
+	app := NewApp(clip)
+	clk := newFakeClock()
+	startWatcher(t, app, clk)
+
+	if d, idle := clk.nextWait(t), time.Duration(app.GetSettings().IdlePollIntervalMs)*time.Millisecond; d != idle {
+		t.Errorf("expected the idle interval %v with notifications, got %v", idle, d)
+	}
+	clip.copyText("hello")
+	clk.nextWait(t)
+	if h := app.GetHistory(); len(h) != 1 || h[0].Text != "hello" {
+		t.Fatalf("expected 'hello' captured, got %+v", h)
+	}
+}
+
+// TestPollClipboard_CopyRightAfterPaste verifies only the change our paste
+// produced is skipped: copies straight after it, even of the pasted text,
+// are captured.
+func TestPollClipboard_CopyRightAfterPaste(t *testing.T) {
+	clip := newFakeBackend()
+	app := NewApp(clip)
+	state := &watchState{lastCount: clip.ChangeCount()}
+	clip.copyText("first")
+	app.pollClipboard(state)
+	clip.copyText("second")
+	app.pollClipboard(state)
+
+	app.SelectItem(app.history[1].ID) // "first"
+	select {
+	case <-clip.pasted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("paste was never simulated")
+	}
+	app.pollClipboard(state)
+
+	clip.copyText("third")
+	app.pollClipboard(state)
+	if h := app.GetHistory(); len(h) != 3 || h[0].Text != "third" {
+		t.Fatalf("expected 'third' captured right after the paste, got %+v", h)
+	}
+
+	clip.copyText("first")
+	app.pollClipboard(state)
+	if h := app.GetHistory(); h[0].Text != "first" {
+		t.Errorf("expected a re-copy of the pasted text to move it to the top, got %q", h[0].Text)
+	}
+}